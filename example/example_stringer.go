@@ -6,6 +6,12 @@ import (
 
 // String Used in fmt to generate string
 func (s *someStruct) String() string {
-	v, _ := json.Marshal(s)
+	if s == nil {
+		return "<nil>"
+	}
+	v, err := json.Marshal(s)
+	if err != nil {
+		return "<someStruct: marshal error: " + err.Error() + ">"
+	}
 	return string(v)
 }
@@ -0,0 +1,604 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// structFields returns the fields of st selected by -fields flag in declaration order, except the ones
+// tagged stringer:"-" and locks. Embedded fields are named after their type and nested, while encoding/json flattens them.
+func (o *output) structFields(st *ast.StructType) []*Field {
+	var fields []*Field
+	for _, f := range st.Fields.List {
+		if isLock(f.Type) {
+			continue
+		}
+		tag := ""
+		if f.Tag != nil {
+			tag, _ = strconv.Unquote(f.Tag.Value)
+		}
+		if len(f.Names) == 0 {
+			name := embeddedName(f.Type)
+			fd := &Field{Name: name, Type: f.Type, Tag: tag, Embedded: true}
+			if o.includeField(name, tag) && o.applyTag(fd) {
+				fields = append(fields, fd)
+			}
+			continue
+		}
+		for _, n := range f.Names {
+			fd := &Field{Name: n.Name, Type: f.Type, Tag: tag}
+			if o.includeField(n.Name, tag) && o.applyTag(fd) {
+				fields = append(fields, fd)
+			}
+		}
+	}
+	return fields
+}
+
+// includeField reports whether the field name with tag is written, exported fields always are.
+// The generated methods are in the same package, so unexported fields can be read by them too.
+func (o *output) includeField(name string, tag string) bool {
+	if name == "" || name == "_" {
+		return false
+	}
+	if ast.IsExported(name) {
+		return true
+	}
+	switch o.fields {
+	case "all":
+		return true
+	case "tagged":
+		_, ok := reflect.StructTag(tag).Lookup("stringer")
+		return ok
+	}
+	return false
+}
+
+// checkFields returns an error if the fields of -fields flag can not be selected by the method,
+// like encoding/json which skips unexported fields, or %+v which prints all of them.
+func (o *output) checkFields() error {
+	switch o.fields {
+	case "", "exported":
+		return nil
+	case "all", "tagged":
+	default:
+		return fmt.Errorf("unknown fields: %s", o.fields)
+	}
+	switch caps := o.caps(); {
+	case caps.Fields:
+	case caps.AllFields:
+		if o.fields == "tagged" {
+			return fmt.Errorf("fields tagged is not supported by method %s, which prints all fields, use -fields=all instead", o.method)
+		}
+	default:
+		return fmt.Errorf("fields %s is not supported by method %s, which does not select fields, use method codegen instead", o.fields, o.method)
+	}
+	return nil
+}
+
+// isLock reports whether typ is a type of sync or sync/atomic like sync.Mutex, a noCopy marker, or a pointer to them.
+// go vet reports copying them, which passing them or the values they point to to a function does, and they hold no data worth printing.
+func isLock(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return isLock(t.X)
+	case *ast.StarExpr:
+		return isLock(t.X)
+	case *ast.Ident:
+		return t.Name == "noCopy"
+	case *ast.IndexExpr:
+		// atomic.Pointer[T]
+		return isLock(t.X)
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		return ok && (pkg.Name == "sync" || pkg.Name == "atomic")
+	}
+	return false
+}
+
+// holdsLock reports whether a value of typ holds a lock of isLock, directly or in the structs and arrays it holds,
+// so that go vet reports copying it. Pointers, slices and maps share what they refer to. seen holds the types visited.
+func (o *output) holdsLock(typ ast.Expr, seen map[string]bool) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return o.holdsLock(t.X, seen)
+	case *ast.ArrayType:
+		return t.Len != nil && o.holdsLock(t.Elt, seen)
+	case *ast.StructType:
+		for _, f := range t.Fields.List {
+			if o.holdsLock(f.Type, seen) {
+				return true
+			}
+		}
+	case *ast.SelectorExpr:
+		return isLock(t)
+	case *ast.Ident, *ast.IndexExpr, *ast.IndexListExpr:
+		name := typeName(t)
+		if isLock(t) || o.locks[name] {
+			return true
+		}
+		if seen[name] || !o.isNamed(name) {
+			return false
+		}
+		seen[name] = true
+		return o.holdsLock(o.underlying(name), seen)
+	}
+	return false
+}
+
+func embeddedName(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	}
+	return ""
+}
+
+// basicKind groups predeclared types by the way they are written.
+func basicKind(name string) string {
+	switch name {
+	case "string":
+		return "string"
+	case "bool":
+		return "bool"
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return "int"
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		return "uint"
+	case "float32", "float64":
+		return "float"
+	case "error":
+		return "error"
+	}
+	return ""
+}
+
+// placeholder returns the text printed for a non-nil value of typ if it is a func or channel type, empty for other types.
+// Their values tell nothing but an address, and go vet reports a func value passed to fmt.Sprint.
+func placeholder(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return placeholder(t.X)
+	case *ast.FuncType:
+		return "<func>"
+	case *ast.ChanType:
+		return "<chan>"
+	}
+	return ""
+}
+
+// jsonKey returns the quoted JSON form of a field name.
+func jsonKey(name string) string {
+	v, _ := json.Marshal(name)
+	return string(v)
+}
+
+// sel returns the selector expression expr.name, a dereference expr is parenthesized.
+func sel(expr string, name string) string {
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")." + name
+	}
+	return expr + "." + name
+}
+
+// goLiteral returns s as a Go string literal, raw strings are preferred for readability.
+func goLiteral(s string) string {
+	if !strings.ContainsAny(s, "`\r") {
+		return "`" + s + "`"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+// body writes statements of a method building its result in strings.Builder sb,
+// or in []byte dst when dst is set, consecutive literals are merged so that the generated code stays short.
+type body struct {
+	o   *output
+	lit strings.Builder
+	dst bool
+}
+
+func (b *body) writeLit(s string) {
+	b.lit.WriteString(s)
+}
+
+func (b *body) flush() {
+	if b.lit.Len() == 0 {
+		return
+	}
+	if b.dst {
+		b.o.addln(fmt.Sprintf("dst = append(dst, %s...)", goLiteral(b.lit.String())))
+	} else {
+		b.o.addln(fmt.Sprintf("sb.WriteString(%s)", goLiteral(b.lit.String())))
+	}
+	b.lit.Reset()
+}
+
+func (b *body) stmt(s string) {
+	b.flush()
+	b.o.addln(s)
+}
+
+// codegen writes the body of a reflection free String or AppendString method which returns JSON.
+type codegen struct {
+	body
+	depth int
+}
+
+// write writes the result of a strconv or strgen function, builder is the function
+// returning string or writing to sb, appender is the one appending to dst.
+func (c *codegen) write(builder string, appender string, args string) {
+	if c.dst {
+		c.stmt(fmt.Sprintf("dst = %s(dst, %s)", appender, args))
+		return
+	}
+	if strings.HasPrefix(builder, "strgen.") {
+		c.stmt(fmt.Sprintf("%s(sb, %s)", builder, args))
+		return
+	}
+	c.stmt(fmt.Sprintf("sb.WriteString(%s(%s))", builder, args))
+}
+
+func (c *codegen) writeString(expr string) {
+	c.write("strgen.WriteString", "strgen.AppendString", c.o.truncate(expr))
+}
+
+func (c *codegen) writeInt(expr string) {
+	c.write("strconv.FormatInt", "strconv.AppendInt", fmt.Sprintf("int64(%s), 10", expr))
+}
+
+func (c *codegen) writeUint(expr string) {
+	c.write("strconv.FormatUint", "strconv.AppendUint", fmt.Sprintf("uint64(%s), 10", expr))
+}
+
+func (c *codegen) writeJSON(expr string) {
+	c.write("strgen.WriteJSON", "strgen.AppendJSON", expr)
+}
+
+// writeNested writes a type of this output named name by its own generated method.
+func (c *codegen) writeNested(expr string, name string) {
+	if c.o.threaded(c.dst) {
+		// pass on the pointers being written and the depth, which only structs add to
+		depth := "depth"
+		if c.o.isStruct(name) {
+			depth = "depth+1"
+		}
+		args := c.o.stateArgs("visited", depth)
+		if c.dst {
+			c.stmt(fmt.Sprintf("dst = %s", sel(expr, "appendString(dst, "+args+")")))
+		} else {
+			c.stmt(sel(expr, "writeString(sb, "+args+")"))
+		}
+		return
+	}
+	if c.dst {
+		c.stmt(fmt.Sprintf("dst = %s", sel(expr, "AppendString(dst)")))
+		return
+	}
+	c.stmt(fmt.Sprintf("sb.WriteString(%s)", sel(expr, "String()")))
+}
+
+// value writes the JSON form of expr whose type is typ.
+func (c *codegen) value(expr string, typ ast.Expr) {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		c.value(expr, t.X)
+	case *ast.Ident:
+		c.ident(expr, t)
+	case *ast.StarExpr:
+		c.stmt(fmt.Sprintf("if %s == nil {", expr))
+		c.writeLit("null")
+		c.stmt("} else {")
+		if c.o.isNamed(typeName(t.X)) {
+			c.writeNested(expr, typeName(t.X))
+		} else {
+			c.value("*"+expr, t.X)
+		}
+		c.stmt("}")
+	case *ast.ArrayType:
+		c.array(expr, t)
+	case *ast.MapType:
+		c.mapType(expr, t)
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		if c.o.isNamed(typeName(t)) {
+			c.writeNested(expr, typeName(t))
+		} else {
+			c.writeJSON(expr)
+		}
+	case *ast.FuncType, *ast.ChanType:
+		// encoding/json fails on them, write the placeholder like logfmt and Format do
+		c.stmt(fmt.Sprintf("if %s == nil {", expr))
+		c.writeLit("null")
+		c.stmt("} else {")
+		c.writeLit(strconv.Quote(placeholder(t)))
+		c.stmt("}")
+	default:
+		c.writeJSON(expr)
+	}
+}
+
+func (c *codegen) ident(expr string, id *ast.Ident) {
+	if c.o.isNamed(id.Name) {
+		c.writeNested(expr, id.Name)
+		return
+	}
+	switch basicKind(id.Name) {
+	case "string":
+		c.writeString(expr)
+	case "bool":
+		c.write("strconv.FormatBool", "strconv.AppendBool", expr)
+	case "int":
+		c.writeInt(expr)
+	case "uint":
+		c.writeUint(expr)
+	case "float":
+		bits := "64"
+		if id.Name == "float32" {
+			bits = "32"
+		}
+		c.write("strgen.WriteFloat", "strgen.AppendFloat", fmt.Sprintf("float64(%s), %s", expr, bits))
+	case "error":
+		c.stmt(fmt.Sprintf("if %s == nil {", expr))
+		c.writeLit("null")
+		c.stmt("} else {")
+		c.writeString(sel(expr, "Error()"))
+		c.stmt("}")
+	default:
+		c.writeJSON(expr)
+	}
+}
+
+func (c *codegen) array(expr string, t *ast.ArrayType) {
+	// []byte is base64 encoded by encoding/json, keep it that way
+	if id, ok := t.Elt.(*ast.Ident); ok && (id.Name == "byte" || id.Name == "uint8") {
+		c.writeJSON(expr)
+		return
+	}
+	i, v := fmt.Sprintf("i%d", c.depth), fmt.Sprintf("v%d", c.depth)
+	c.depth++
+	defer func() { c.depth-- }()
+	if t.Len == nil {
+		c.stmt(fmt.Sprintf("if %s == nil {", expr))
+		c.writeLit("null")
+		c.stmt("} else {")
+		defer c.stmt("}")
+	}
+	c.writeLit("[")
+	c.stmt(fmt.Sprintf("for %s, %s := range %s {", i, v, expr))
+	c.stmt(fmt.Sprintf("if %s > 0 {", i))
+	c.writeLit(",")
+	c.stmt("}")
+	c.more(i, expr, "")
+	c.value(v, t.Elt)
+	c.stmt("}")
+	c.writeLit("]")
+}
+
+func (c *codegen) mapType(expr string, t *ast.MapType) {
+	keys := sortedKeys(t.Key)
+	if keys == "" {
+		c.writeJSON(expr)
+		return
+	}
+	key := t.Key.(*ast.Ident)
+	i, k, v := fmt.Sprintf("i%d", c.depth), fmt.Sprintf("k%d", c.depth), fmt.Sprintf("v%d", c.depth)
+	c.depth++
+	defer func() { c.depth-- }()
+	c.stmt(fmt.Sprintf("if %s == nil {", expr))
+	c.writeLit("null")
+	c.stmt("} else {")
+	c.writeLit("{")
+	// keys are sorted like encoding/json does, so that the output is stable and -max-elems keeps the first ones
+	c.stmt(fmt.Sprintf("for %s, %s := range strgen.%s(%s) {", i, k, keys, expr))
+	c.stmt(fmt.Sprintf("if %s > 0 {", i))
+	c.writeLit(",")
+	c.stmt("}")
+	// the elided elements take the place of a key
+	c.more(i, expr, ":null")
+	c.stmt(fmt.Sprintf("%s := %s", v, index(expr, k)))
+	switch basicKind(key.Name) {
+	case "string":
+		c.writeString(k)
+	case "int":
+		c.writeLit(`"`)
+		c.writeInt(k)
+		c.writeLit(`"`)
+	case "uint":
+		c.writeLit(`"`)
+		c.writeUint(k)
+		c.writeLit(`"`)
+	}
+	c.writeLit(":")
+	c.value(v, t.Value)
+	c.stmt("}")
+	c.writeLit("}")
+	c.stmt("}")
+}
+
+// sortedKeys returns the strgen function returning the keys of a map whose key type is key in the order
+// encoding/json writes them, empty if the keys are not strings or integers.
+func sortedKeys(key ast.Expr) string {
+	id, ok := key.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	return map[string]string{"string": "StringKeys", "int": "IntKeys", "uint": "UintKeys"}[basicKind(id.Name)]
+}
+
+// index returns the index expression expr[key], a dereference expr is parenthesized.
+func index(expr string, key string) string {
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")[" + key + "]"
+	}
+	return expr + "[" + key + "]"
+}
+
+// more writes "... 42 more" in place of the rest elements of expr when i reaches -max-elems, followed by suffix.
+func (c *codegen) more(i string, expr string, suffix string) {
+	if c.o.maxElems == 0 {
+		return
+	}
+	c.stmt(fmt.Sprintf("if %s == %d {", i, c.o.maxElems))
+	c.write("strgen.WriteMore", "strgen.AppendMore", fmt.Sprintf("len(%s)-%d", expr, c.o.maxElems))
+	c.writeLit(suffix)
+	c.stmt("break")
+	c.stmt("}")
+}
+
+// object writes the JSON of type name whose receiver is n, it is an object for structs.
+func (c *codegen) object(n string, name string) {
+	if !c.o.isStruct(name) {
+		expr := c.o.deref(n)
+		if c.o.threaded(c.dst) {
+			// writeString and appendString have pointer receivers
+			expr = "*" + n
+		}
+		c.value(expr, c.o.underlying(name))
+		c.flush()
+		return
+	}
+	c.writeLit("{")
+	fields := c.o.structFields(c.o.structType(name))
+	sep := &separator{b: &c.body, sep: ","}
+	for j, f := range fields {
+		expr := n + "." + f.Name
+		cond := ""
+		if f.OmitEmpty {
+			cond = c.o.notEmpty(expr, f.Type)
+		}
+		sep.field(cond, j < len(fields)-1, func() {
+			c.writeLit(jsonKey(f.Key) + ":")
+			if f.Redact == "full" {
+				c.writeLit(jsonKey(redacted))
+			} else if f.Redact != "" {
+				c.write("strgen.WriteString", "strgen.AppendString", f.redaction(expr))
+			} else {
+				c.value(expr, f.Type)
+			}
+		})
+	}
+	c.writeLit("}")
+	c.flush()
+}
+
+// genCodegen writes a String method building JSON with strings.Builder.
+func (o *output) genCodegen(name string) {
+	o.codegenString(name, o.indent)
+}
+
+// genPretty writes a String method like codegen which returns JSON indented by -indent, or two spaces by default.
+func (o *output) genPretty(name string) {
+	indent := o.indent
+	if indent == "" {
+		indent = "  "
+	}
+	o.codegenString(name, indent)
+}
+
+func (o *output) codegenString(name string, indent string) {
+	n := strings.ToLower(name[0:1])
+	o.addln("// String Used in fmt to generate string")
+	o.addln(fmt.Sprintf("func (%s) String() string {", o.recv(name)))
+	o.nilGuard(n, `"<nil>"`)
+	o.addln("sb := &strings.Builder{}")
+	if o.threaded(false) {
+		o.addln(fmt.Sprintf("%s.writeString(sb, %s)", n, o.stateArgs("&strgen.Visited{}", "0")))
+	} else {
+		c := &codegen{body: body{o: o}}
+		c.object(n, name)
+	}
+	if indent != "" {
+		o.returnString(fmt.Sprintf("strgen.IndentJSON(sb.String(), %q)", indent))
+	} else {
+		o.returnString("sb.String()")
+	}
+	o.addln("}")
+	if o.threaded(false) {
+		o.addln("")
+		o.nestedMethod(name, false)
+	}
+}
+
+// genAppend writes an AppendString method appending JSON to dst and a String method on top of it.
+func (o *output) genAppend(name string) {
+	n := strings.ToLower(name[0:1])
+	o.addln("// AppendString appends the string to dst and returns the extended buffer")
+	o.addln(fmt.Sprintf("func (%s) AppendString(dst []byte) []byte {", o.recv(name)))
+	o.nilGuard(n, `append(dst, "<nil>"...)`)
+	if o.threaded(true) {
+		o.addln(fmt.Sprintf("return %s.appendString(dst, %s)", n, o.stateArgs("&strgen.Visited{}", "0")))
+	} else {
+		c := &codegen{body: body{o: o, dst: true}}
+		c.object(n, name)
+		o.addln("return dst")
+	}
+	o.addln("}")
+	o.addln("")
+	o.addln("// String Used in fmt to generate string")
+	o.addln(fmt.Sprintf("func (%s) String() string {", o.recv(name)))
+	if o.indent != "" {
+		// AppendString stays compact, so that nested structs are not indented twice
+		o.returnString(fmt.Sprintf("strgen.IndentJSON(string(%s.AppendString(nil)), %q)", n, o.indent))
+	} else {
+		o.returnString(fmt.Sprintf("string(%s.AppendString(nil))", n))
+	}
+	o.addln("}")
+	if o.threaded(true) {
+		o.addln("")
+		o.nestedMethod(name, true)
+	}
+}
+
+// isNamed reports whether name is a type that gets a String method in this output.
+func (o *output) isNamed(name string) bool {
+	if o.params[name] {
+		return false
+	}
+	_, ok := o.specs[name]
+	return ok
+}
+
+// isStruct reports whether name is a struct that gets a String method in this output.
+func (o *output) isStruct(name string) bool {
+	return o.structType(name) != nil
+}
+
+// structType returns the struct type of name, nil if name is not a struct in this output.
+func (o *output) structType(name string) *ast.StructType {
+	st, _ := o.underlying(name).(*ast.StructType)
+	return st
+}
+
+// underlying returns the type expression of name in this output, types defined by another type
+// in this output are resolved, like the struct type of Alias for type Alias Other.
+func (o *output) underlying(name string) ast.Expr {
+	ts, ok := o.specs[name]
+	if !ok || o.params[name] {
+		return nil
+	}
+	typ := ts.Type
+	// a cycle of defined types is not valid Go, stop after every type is visited
+	for i := 0; i < len(o.specs); i++ {
+		id, ok := typ.(*ast.Ident)
+		if !ok {
+			return typ
+		}
+		ts, ok := o.specs[id.Name]
+		if !ok {
+			return typ
+		}
+		typ = ts.Type
+	}
+	return typ
+}
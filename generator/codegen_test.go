@@ -0,0 +1,537 @@
+package generator
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parseOutput parses src and returns the output for its structs.
+func parseOutput(t *testing.T, src string, method string) *output {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parser.ParseFile() error: %v", err)
+	}
+	out, err := parseFile(file, nil, &genOptions{method: method})
+	if err != nil {
+		t.Fatalf("parseFile() error: %v", err)
+	}
+	return out
+}
+
+// genFile writes the header and the methods of the structs by method like gen does, but the code is not
+// formatted, so that the expected code can be compared line by line.
+func genFile(o *output, method string) error {
+	imports, err := o.imports(methods[method])
+	if err != nil {
+		return err
+	}
+	o.header(imports)
+	return o.genStructs(methods[method])
+}
+
+// checkFset and checkImporter are shared by typeCheck, so that imported packages are type-checked once.
+var (
+	checkFset     = token.NewFileSet()
+	checkImporter = importer.ForCompiler(checkFset, "source", nil)
+)
+
+// typeCheck type-checks src and the code generated for it as one package.
+func typeCheck(t *testing.T, src string, generated []byte) {
+	t.Helper()
+	var files []*ast.File
+	for i, s := range []string{src, string(generated)} {
+		file, err := parser.ParseFile(checkFset, []string{"src.go", "src_stringer.go"}[i], s, 0)
+		if err != nil {
+			t.Fatalf("parser.ParseFile() error: %v\n%s", err, s)
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: checkImporter}
+	if _, err := conf.Check("main", checkFset, files, nil); err != nil {
+		t.Errorf("types.Config.Check() error: %v\n%s", err, generated)
+	}
+}
+
+// run builds src, the code generated for it and mainSrc, which declares func main, as one program,
+// and returns what it prints. go vet has to pass too.
+func run(t *testing.T, src string, generated []byte, mainSrc string) string {
+	t.Helper()
+	// the program is in the module, so that the strgen import is resolved
+	if _, err := os.Stat("testdata"); os.IsNotExist(err) {
+		if err := os.Mkdir("testdata", 0o755); err != nil {
+			t.Fatalf("os.Mkdir() error: %v", err)
+		}
+		// cleanups run last in first out, so it is removed after the programs in it
+		t.Cleanup(func() { _ = os.Remove("testdata") })
+	}
+	dir, err := os.MkdirTemp("testdata", "run")
+	if err != nil {
+		t.Fatalf("os.MkdirTemp() error: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	files := map[string]string{"src.go": src, "src_stringer.go": string(generated), "main.go": mainSrc}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error: %v", err)
+		}
+	}
+	for _, args := range [][]string{{"vet", "."}, {"run", "."}} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go %s error: %v\n%s\n%s", args[0], err, out, generated)
+		}
+		if args[0] == "run" {
+			return string(out)
+		}
+	}
+	return ""
+}
+
+func TestStructFields(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A, B int
+	c    string
+	Sub
+	*pkg.Other
+	_ int
+}
+`, "")
+
+	var names []string
+	for _, f := range o.structFields(o.specs["MyStruct"].Type.(*ast.StructType)) {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"A", "B", "Sub", "Other"}, names)
+}
+
+func TestStructFieldsUnexported(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A     int
+	b     string
+	c     bool `+"`stringer:\"c\"`"+`
+	inner
+	_ int
+}
+`, "")
+	st := o.specs["MyStruct"].Type.(*ast.StructType)
+
+	for fields, want := range map[string][]string{
+		"":         {"A"},
+		"exported": {"A"},
+		"all":      {"A", "b", "c", "inner"},
+		"tagged":   {"A", "c"},
+	} {
+		o.fields = fields
+		var names []string
+		for _, f := range o.structFields(st) {
+			names = append(names, f.Name)
+		}
+		assert.Equal(t, want, names, fields)
+	}
+}
+
+func TestStructFieldsLocks(t *testing.T) {
+	src := `
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type MyStruct struct {
+	mu    sync.RWMutex
+	once  *sync.Once
+	count atomic.Int64
+	ptr   atomic.Pointer[int]
+	name  string
+	sync.Mutex
+	_ noCopy
+}
+
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+`
+	o := parseOutput(t, src, "")
+	o.fields = "all"
+	var names []string
+	for _, f := range o.structFields(o.structType("MyStruct")) {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"name"}, names)
+
+	// go vet reports locks passed by value
+	for _, method := range []string{"codegen", "append", "logfmt", "slog", "zap"} {
+		o := parseOutput(t, src, method)
+		o.fields = "all"
+		o.formatter = method != "slog" && method != "zap"
+		o.structNames = o.structNames[:1]
+		res, err := o.gen()
+		assert.NoError(t, err)
+		run(t, src, res, "package main\n\nfunc main() {}\n")
+	}
+}
+
+func TestCheckFields(t *testing.T) {
+	tests := []struct {
+		method  string
+		fields  string
+		wantErr string
+	}{
+		{method: "json", fields: "exported"},
+		{method: "codegen", fields: "all"},
+		{method: "zap", fields: "tagged"},
+		{method: "fmt", fields: "all"},
+		{method: "codegen", fields: "some", wantErr: "unknown fields: some"},
+		{method: "json", fields: "all", wantErr: "fields all is not supported by method json"},
+		{method: "jsoniter", fields: "tagged", wantErr: "fields tagged is not supported by method jsoniter"},
+		{method: "retype", fields: "tagged", wantErr: "fields tagged is not supported by method retype"},
+	}
+
+	for _, tt := range tests {
+		o := &output{method: tt.method, fields: tt.fields}
+		err := o.checkFields()
+		if tt.wantErr == "" {
+			assert.NoError(t, err, tt.method)
+		} else if assert.Error(t, err, tt.method) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	}
+}
+
+func TestGenCodegen(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+	Field2 int
+	Field3 *Sub
+	Field4 []bool
+	Field5 map[string]float32
+	Field6 time.Time
+}
+
+type Sub struct{}
+`, "codegen")
+	o.structNames = o.structNames[:1]
+
+	err := genFile(o, "codegen")
+	assert.NoError(t, err)
+
+	expected := "package main\n" +
+		"\n" +
+		"import (\n" +
+		"\"strconv\"\n" +
+		"\"strings\"\n" +
+		"\n" +
+		"\"github.com/chasemao/stringergen/strgen\"\n" +
+		")\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (m *MyStruct) String() string {\n" +
+		"if m == nil {\n" +
+		"return \"<nil>\"\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"sb.WriteString(`{\"Field1\":`)\n" +
+		"strgen.WriteString(sb, m.Field1)\n" +
+		"sb.WriteString(`,\"Field2\":`)\n" +
+		"sb.WriteString(strconv.FormatInt(int64(m.Field2), 10))\n" +
+		"sb.WriteString(`,\"Field3\":`)\n" +
+		"if m.Field3 == nil {\n" +
+		"sb.WriteString(`null`)\n" +
+		"} else {\n" +
+		"sb.WriteString(m.Field3.String())\n" +
+		"}\n" +
+		"sb.WriteString(`,\"Field4\":`)\n" +
+		"if m.Field4 == nil {\n" +
+		"sb.WriteString(`null`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`[`)\n" +
+		"for i0, v0 := range m.Field4 {\n" +
+		"if i0 > 0 {\n" +
+		"sb.WriteString(`,`)\n" +
+		"}\n" +
+		"sb.WriteString(strconv.FormatBool(v0))\n" +
+		"}\n" +
+		"sb.WriteString(`]`)\n" +
+		"}\n" +
+		"sb.WriteString(`,\"Field5\":`)\n" +
+		"if m.Field5 == nil {\n" +
+		"sb.WriteString(`null`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`{`)\n" +
+		"for i0, k0 := range strgen.StringKeys(m.Field5) {\n" +
+		"if i0 > 0 {\n" +
+		"sb.WriteString(`,`)\n" +
+		"}\n" +
+		"v0 := m.Field5[k0]\n" +
+		"strgen.WriteString(sb, k0)\n" +
+		"sb.WriteString(`:`)\n" +
+		"strgen.WriteFloat(sb, float64(v0), 32)\n" +
+		"}\n" +
+		"sb.WriteString(`}`)\n" +
+		"}\n" +
+		"sb.WriteString(`,\"Field6\":`)\n" +
+		"strgen.WriteJSON(sb, m.Field6)\n" +
+		"sb.WriteString(`}`)\n" +
+		"return sb.String()\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenCodegenCompiles(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	A [2]*int
+	B map[int][]*MyStruct
+	C error
+	D interface{}
+	E []byte
+	F func() error
+	G chan int
+}
+`
+	o := parseOutput(t, src, "codegen")
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	typeCheck(t, src, res)
+	// encoding/json fails on funcs and channels
+	assert.Contains(t, string(res), "sb.WriteString(`\"<func>\"`)")
+	assert.Contains(t, string(res), "sb.WriteString(`\"<chan>\"`)")
+}
+
+func TestGenCodegenRuns(t *testing.T) {
+	src := `
+package main
+
+import "time"
+
+type Item struct {
+	Name    string
+	Count   int
+	Ratio   float64
+	Tags    []string
+	Attrs   map[string]int
+	Ranks   map[int]bool
+	Sub     *Sub
+	Subs    []Sub
+	Err     error
+	Any     interface{}
+	Raw     []byte
+	When    time.Time
+	Handler func()
+	Events  chan int
+}
+
+type Sub struct {
+	ID int
+}
+`
+	mainSrc := `
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+func main() {
+	item := &Item{
+		Name:    "a\"b\n<\u00e9>\u2028",
+		Count:   -1,
+		Ratio:   math.NaN(),
+		Tags:    []string{"x", ""},
+		Attrs:   map[string]int{"b": 2, "a": 1, "c": 3},
+		Ranks:   map[int]bool{9: true, 10: false},
+		Sub:     &Sub{ID: 1},
+		Subs:    []Sub{{ID: 2}},
+		Err:     errors.New("failed"),
+		Any:     map[string]int{"x": 1},
+		Raw:     []byte("raw"),
+		Handler: func() {},
+		Events:  make(chan int),
+	}
+	fmt.Println(item.String())
+	fmt.Println((&Item{}).String())
+}
+`
+	for _, method := range []string{"codegen", "pretty", "append"} {
+		o := parseOutput(t, src, method)
+		res, err := o.gen()
+		assert.NoError(t, err)
+		out := run(t, src, res, mainSrc)
+		// values of pretty span lines, read them one by one
+		dec := json.NewDecoder(strings.NewReader(out))
+		for i := 0; i < 2; i++ {
+			var v json.RawMessage
+			if assert.NoError(t, dec.Decode(&v), "%s: %s", method, out) {
+				assert.True(t, json.Valid(v), "%s: %s", method, v)
+			}
+		}
+		compact := strings.Join(strings.Fields(out), "")
+		assert.Contains(t, compact, `"Attrs":{"a":1,"b":2,"c":3},"Ranks":{"10":false,"9":true}`, method)
+		assert.Contains(t, compact, `"Handler":"<func>","Events":"<chan>"`, method)
+	}
+}
+
+func TestGenAppend(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+	Field2 uint8
+	Field3 *Sub
+	Field4 map[int]bool
+}
+
+type Sub struct{}
+`, "append")
+	o.structNames = o.structNames[:1]
+
+	err := genFile(o, "append")
+	assert.NoError(t, err)
+
+	expected := "package main\n" +
+		"\n" +
+		"import (\n" +
+		"\"strconv\"\n" +
+		"\n" +
+		"\"github.com/chasemao/stringergen/strgen\"\n" +
+		")\n" +
+		"\n" +
+		"// AppendString appends the string to dst and returns the extended buffer\n" +
+		"func (m *MyStruct) AppendString(dst []byte) []byte {\n" +
+		"if m == nil {\n" +
+		"return append(dst, \"<nil>\"...)\n" +
+		"}\n" +
+		"dst = append(dst, `{\"Field1\":`...)\n" +
+		"dst = strgen.AppendString(dst, m.Field1)\n" +
+		"dst = append(dst, `,\"Field2\":`...)\n" +
+		"dst = strconv.AppendUint(dst, uint64(m.Field2), 10)\n" +
+		"dst = append(dst, `,\"Field3\":`...)\n" +
+		"if m.Field3 == nil {\n" +
+		"dst = append(dst, `null`...)\n" +
+		"} else {\n" +
+		"dst = m.Field3.AppendString(dst)\n" +
+		"}\n" +
+		"dst = append(dst, `,\"Field4\":`...)\n" +
+		"if m.Field4 == nil {\n" +
+		"dst = append(dst, `null`...)\n" +
+		"} else {\n" +
+		"dst = append(dst, `{`...)\n" +
+		"for i0, k0 := range strgen.IntKeys(m.Field4) {\n" +
+		"if i0 > 0 {\n" +
+		"dst = append(dst, `,`...)\n" +
+		"}\n" +
+		"v0 := m.Field4[k0]\n" +
+		"dst = append(dst, `\"`...)\n" +
+		"dst = strconv.AppendInt(dst, int64(k0), 10)\n" +
+		"dst = append(dst, `\":`...)\n" +
+		"dst = strconv.AppendBool(dst, v0)\n" +
+		"}\n" +
+		"dst = append(dst, `}`...)\n" +
+		"}\n" +
+		"dst = append(dst, `}`...)\n" +
+		"return dst\n" +
+		"}\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (m *MyStruct) String() string {\n" +
+		"return string(m.AppendString(nil))\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenCodegenNamedType(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type Users []*User
+
+type Admin User
+
+type User struct {
+	Name string
+}
+`, "codegen")
+	o.structNames = o.structNames[:2]
+
+	err := genFile(o, "codegen")
+	assert.NoError(t, err)
+
+	expected := "package main\n" +
+		"\n" +
+		"import (\n" +
+		"\"strconv\"\n" +
+		"\"strings\"\n" +
+		"\n" +
+		"\"github.com/chasemao/stringergen/strgen\"\n" +
+		")\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (u *Users) String() string {\n" +
+		"if u == nil {\n" +
+		"return \"<nil>\"\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"if *u == nil {\n" +
+		"sb.WriteString(`null`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`[`)\n" +
+		"for i0, v0 := range *u {\n" +
+		"if i0 > 0 {\n" +
+		"sb.WriteString(`,`)\n" +
+		"}\n" +
+		"if v0 == nil {\n" +
+		"sb.WriteString(`null`)\n" +
+		"} else {\n" +
+		"sb.WriteString(v0.String())\n" +
+		"}\n" +
+		"}\n" +
+		"sb.WriteString(`]`)\n" +
+		"}\n" +
+		"return sb.String()\n" +
+		"}\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (a *Admin) String() string {\n" +
+		"if a == nil {\n" +
+		"return \"<nil>\"\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"sb.WriteString(`{\"Name\":`)\n" +
+		"strgen.WriteString(sb, a.Name)\n" +
+		"sb.WriteString(`}`)\n" +
+		"return sb.String()\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+}
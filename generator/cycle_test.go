@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenCycle(t *testing.T) {
+	src := `
+package main
+
+type Node struct {
+	Next     *Node
+	Children Nodes
+}
+
+type Nodes []*Node
+`
+	o := parseOutput(t, src, "codegen")
+	o.cycle = true
+	o.structNames = o.structNames[:1]
+	err := genFile(o, "codegen")
+	assert.NoError(t, err)
+	expected := `package main
+
+import (
+"strconv"
+"strings"
+
+"github.com/chasemao/stringergen/strgen"
+)
+
+// String Used in fmt to generate string
+func (n *Node) String() string {
+if n == nil {
+return "<nil>"
+}
+sb := &strings.Builder{}
+n.writeString(sb, &strgen.Visited{})
+return sb.String()
+}
+
+// writeString writes the string to sb, pointers in visited are being written, meeting one of them again is a cycle
+func (n *Node) writeString(sb *strings.Builder, visited *strgen.Visited) {
+if !visited.Enter(n) {
+sb.WriteString(` + "`" + `"<cycle *Node>"` + "`" + `)
+return
+}
+defer visited.Leave(n)
+sb.WriteString(` + "`" + `{"Next":` + "`" + `)
+if n.Next == nil {
+sb.WriteString(` + "`" + `null` + "`" + `)
+} else {
+n.Next.writeString(sb, visited)
+}
+sb.WriteString(` + "`" + `,"Children":` + "`" + `)
+n.Children.writeString(sb, visited)
+sb.WriteString(` + "`" + `}` + "`" + `)
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+
+	o = parseOutput(t, src, "append")
+	o.cycle = true
+	o.receiver = "value"
+	res, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), "func (n Nodes) AppendString(dst []byte) []byte {\n\treturn n.appendString(dst, &strgen.Visited{})")
+	assert.Contains(t, string(res), "func (n *Nodes) appendString(dst []byte, visited *strgen.Visited) []byte {")
+	assert.Contains(t, string(res), "for i0, v0 := range *n {")
+	assert.Contains(t, string(res), "dst = v0.appendString(dst, visited)")
+	assert.Contains(t, string(res), "return append(dst, `\"<cycle *Node>\"`...)")
+
+	o = parseOutput(t, src, "logfmt")
+	o.cycle = true
+	_, err = o.gen()
+	assert.EqualError(t, err, "cycle is not supported by method logfmt")
+
+	for _, set := range []func(o *output){func(o *output) { o.formatter = true }, func(o *output) { o.goString = true }} {
+		o = parseOutput(t, src, "codegen")
+		o.cycle = true
+		set(o)
+		_, err = o.gen()
+		assert.EqualError(t, err, "cycle is not supported with formatter or gostring, whose methods do not track the pointers being written")
+	}
+}
+
+func TestGenCycleRuns(t *testing.T) {
+	src := `
+package main
+
+type Node struct {
+	Name     string
+	Next     *Node
+	Children Nodes
+}
+
+type Nodes []*Node
+`
+	mainSrc := `
+package main
+
+import "fmt"
+
+func main() {
+	a := &Node{Name: "a"}
+	b := &Node{Name: "b", Next: a}
+	a.Next = a
+	a.Children = Nodes{b, b}
+	fmt.Println(a.String())
+}
+`
+	for _, tt := range []struct {
+		method   string
+		receiver string
+		want     string
+	}{
+		{
+			method: "codegen",
+			want:   `{"Name":"a","Next":"<cycle *Node>","Children":[{"Name":"b","Next":"<cycle *Node>","Children":null},{"Name":"b","Next":"<cycle *Node>","Children":null}]}`,
+		},
+		{
+			method: "append",
+			want:   `{"Name":"a","Next":"<cycle *Node>","Children":[{"Name":"b","Next":"<cycle *Node>","Children":null},{"Name":"b","Next":"<cycle *Node>","Children":null}]}`,
+		},
+		{
+			// the receiver is a copy, so the cycle back to it is found one level deeper
+			method:   "codegen",
+			receiver: "value",
+			want:     `{"Name":"a","Next":{"Name":"a","Next":"<cycle *Node>","Children":[{"Name":"b","Next":"<cycle *Node>","Children":null},{"Name":"b","Next":"<cycle *Node>","Children":null}]},"Children":[{"Name":"b","Next":{"Name":"a","Next":"<cycle *Node>","Children":["<cycle *Node>","<cycle *Node>"]},"Children":null},{"Name":"b","Next":{"Name":"a","Next":"<cycle *Node>","Children":["<cycle *Node>","<cycle *Node>"]},"Children":null}]}`,
+		},
+	} {
+		o := parseOutput(t, src, tt.method)
+		o.cycle = true
+		o.receiver = tt.receiver
+		res, err := o.gen()
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want+"\n", run(t, src, res, mainSrc), "%s %s", tt.method, tt.receiver)
+	}
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+)
+
+// parseDeclared returns the names of methods declared in the package of source by receiver type name.
+// All files of the directory in package pkg are read, except destination which is overwritten,
+// files which can not be parsed are skipped, they are reported by the compiler anyway.
+func parseDeclared(source string, destination string, pkg string) (map[string]map[string]bool, error) {
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(source), "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	dst := ""
+	if destination != "" {
+		if dst, err = filepath.Abs(destination); err != nil {
+			return nil, err
+		}
+	}
+	declared := make(map[string]map[string]bool)
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err != nil || abs == dst {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			d.Printf(yellow+"SKIP PACKAGE FILE: %s, %v"+reset, path, err)
+			continue
+		}
+		// external test packages like foo_test can not declare methods of pkg
+		if file.Name.Name != pkg {
+			continue
+		}
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 {
+				continue
+			}
+			typ := fd.Recv.List[0].Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			name := typeName(typ)
+			if declared[name] == nil {
+				declared[name] = make(map[string]bool)
+			}
+			declared[name][fd.Name.Name] = true
+		}
+	}
+	return declared, nil
+}
+
+// mainMethods returns the methods written for type name by its method.
+func (o *output) mainMethods(name string) []string {
+	switch o.methodOf(name) {
+	case "slog":
+		return []string{"LogValue"}
+	case "zap":
+		if o.zapMarshaler(name) == "array" {
+			return []string{"MarshalLogArray"}
+		}
+		return []string{"MarshalLogObject"}
+	case "append":
+		return []string{"AppendString", "String"}
+	}
+	return []string{"String"}
+}
+
+// skipDeclared removes the types and enums which already have the methods of their methods in the package,
+// so that the generated file does not declare them twice.
+func (o *output) skipDeclared(declared map[string]map[string]bool) {
+	o.declared = declared
+	var names []string
+	for _, name := range o.structNames {
+		if m := o.declaredMethod(name, o.mainMethods(name)...); m != "" {
+			d.Printf(yellow+"SKIP STRUCT: %s already has method %s in package"+reset, name, m)
+			// it is written like a type of another package by the other types
+			if ts, ok := o.specs[name]; ok {
+				o.foreign[name] = ts
+			}
+			delete(o.specs, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	o.structNames = names
+	var enums []*enum
+	for _, e := range o.enums {
+		if m := o.declaredMethod(e.name, "String"); m != "" {
+			d.Printf(yellow+"SKIP ENUM: %s already has method %s in package"+reset, e.name, m)
+			continue
+		}
+		enums = append(enums, e)
+	}
+	o.enums = enums
+}
+
+// undeclared returns the structs which do not have method m in the package, the others are reported.
+func (o *output) undeclared(m string) []string {
+	var names []string
+	for _, name := range o.structs() {
+		if o.declaredMethod(name, m) != "" {
+			d.Printf(yellow+"SKIP METHOD: %s already has method %s in package"+reset, name, m)
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// declaredMethod returns the first of ms which type name already has in the package, empty if none.
+func (o *output) declaredMethod(name string, ms ...string) string {
+	for _, m := range ms {
+		if o.declared[name][m] {
+			return m
+		}
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestParseDeclared(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"types.go": `package main
+
+type User struct{}
+
+type Page[T any] struct{}
+`,
+		"user.go": `package main
+
+func (u *User) String() string { return "" }
+
+func (p Page[T]) GoString() string { return "" }
+
+func helper() {}
+`,
+		"types_stringer.go": `package main
+
+func (u *User) Format() {}
+`,
+		"user_test.go": `package main_test
+
+func (u *User) LogValue() {}
+`,
+		"broken.go": `package main
+
+func (u *User) Broken( {}
+`,
+	})
+
+	declared, err := parseDeclared(filepath.Join(dir, "types.go"), filepath.Join(dir, "types_stringer.go"), "main")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]bool{
+		"User": {"String": true},
+		"Page": {"GoString": true},
+	}, declared)
+}
+
+func TestSkipDeclared(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type User struct{}
+
+type Admin struct {
+	User User
+}
+
+type Page struct{}
+`, "append")
+	o.enums = []*enum{{name: "Status"}, {name: "Kind"}}
+	o.goString = true
+
+	o.skipDeclared(map[string]map[string]bool{
+		"User":   {"String": true},
+		"Page":   {"GoString": true},
+		"Status": {"String": true},
+	})
+
+	assert.Equal(t, []string{"Admin", "Page"}, o.structNames)
+	assert.Equal(t, []*enum{{name: "Kind"}}, o.enums)
+	assert.False(t, o.isNamed("User"))
+	assert.Equal(t, []string{"Admin"}, o.undeclared("GoString"))
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(res), "func (u *User)")
+	assert.NotContains(t, string(res), "func (p *Page) GoString()")
+	// User is written like a type of another package
+	assert.Contains(t, string(res), "dst = strgen.AppendJSON(dst, a.User)")
+}
+
+func TestGenSourceDeclared(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"types.go": `package main
+
+type User struct {
+	Name string
+}
+
+type Admin struct {
+	Name string
+}
+`,
+		"user.go": `package main
+
+func (u User) String() string { return u.Name }
+`,
+	})
+	dst := filepath.Join(dir, "types_stringer.go")
+
+	// the destination of the last run is overwritten, its methods are not declared
+	for i := 0; i < 2; i++ {
+		err := genSource(filepath.Join(dir, "types.go"), dst, nil, &genOptions{method: "json"})
+		assert.NoError(t, err)
+		res, err := os.ReadFile(dst)
+		assert.NoError(t, err)
+		assert.Contains(t, string(res), "func (a *Admin) String() string {")
+		assert.NotContains(t, string(res), "User")
+	}
+}
@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// directivePrefix starts the comment directives in the doc comment of a type like //stringergen:skip.
+const directivePrefix = "//stringergen:"
+
+// parseDirectives returns the directives of type name in its doc comments, which are the one of the type declaration
+// and the one of the type spec in a grouped declaration. skip is set by //stringergen:skip, method by
+// //stringergen:method=fmt, the directive of the spec wins.
+func parseDirectives(name string, docs ...*ast.CommentGroup) (skip bool, method string, err error) {
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, c := range doc.List {
+			if !strings.HasPrefix(c.Text, directivePrefix) {
+				continue
+			}
+			switch directive := strings.TrimSpace(strings.TrimPrefix(c.Text, directivePrefix)); {
+			case directive == "skip":
+				skip = true
+			case strings.HasPrefix(directive, "method="):
+				method = strings.TrimPrefix(directive, "method=")
+				if _, ok := methods[method]; !ok {
+					return false, "", fmt.Errorf("unknown method %s in directive of type %s", method, name)
+				}
+			default:
+				return false, "", fmt.Errorf("unknown directive %s of type %s", c.Text, name)
+			}
+		}
+	}
+	return skip, method, nil
+}
+
+// methodOf returns the method writing type name, which is the one of //stringergen:method directive
+// or -method flag.
+func (o *output) methodOf(name string) string {
+	if m, ok := o.typeMethods[name]; ok {
+		return m
+	}
+	return o.method
+}
+
+// methodGroup holds the types written by the same method.
+type methodGroup struct {
+	method string
+	names  []string
+	specs  map[string]*ast.TypeSpec
+}
+
+// methodGroups returns the types grouped by their methods in order, the group of -method flag comes first.
+func (o *output) methodGroups() []*methodGroup {
+	groups := []*methodGroup{{method: o.method, specs: make(map[string]*ast.TypeSpec)}}
+	byMethod := map[string]*methodGroup{o.method: groups[0]}
+	for _, name := range o.structNames {
+		m := o.methodOf(name)
+		g, ok := byMethod[m]
+		if !ok {
+			g = &methodGroup{method: m, specs: make(map[string]*ast.TypeSpec)}
+			byMethod[m] = g
+			groups = append(groups, g)
+		}
+		g.names = append(g.names, name)
+		if ts, ok := o.specs[name]; ok {
+			g.specs[name] = ts
+		}
+	}
+	if len(groups[0].names) == 0 && len(groups) > 1 {
+		// every type has a method directive
+		return groups[1:]
+	}
+	return groups
+}
+
+// use makes the output write the types of g by its method, the types of other groups are written
+// like types of another package by them.
+func (o *output) use(g *methodGroup) {
+	o.method = g.method
+	o.structNames = g.names
+	o.specs = g.specs
+}
@@ -0,0 +1,245 @@
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDirectives(t *testing.T) {
+	doc := func(lines ...string) *ast.CommentGroup {
+		g := &ast.CommentGroup{}
+		for _, l := range lines {
+			g.List = append(g.List, &ast.Comment{Text: l})
+		}
+		return g
+	}
+
+	skip, method, err := parseDirectives("T", doc("// T is a type.", "//stringergen:skip"))
+	assert.NoError(t, err)
+	assert.True(t, skip)
+	assert.Equal(t, "", method)
+
+	// the directive of the spec wins
+	skip, method, err = parseDirectives("T", doc("//stringergen:method=fmt"), nil, doc("//stringergen:method=codegen"))
+	assert.NoError(t, err)
+	assert.False(t, skip)
+	assert.Equal(t, "codegen", method)
+
+	// a comment mentioning the directive is not one
+	_, method, err = parseDirectives("T", doc("// see //stringergen:method=fmt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", method)
+
+	_, _, err = parseDirectives("T", doc("//stringergen:method=none"))
+	assert.EqualError(t, err, "unknown method none in directive of type T")
+	_, _, err = parseDirectives("T", doc("//stringergen:ignore"))
+	assert.EqualError(t, err, "unknown directive //stringergen:ignore of type T")
+}
+
+func TestGenDirectives(t *testing.T) {
+	src := `
+package main
+
+type Request struct {
+	ID    int
+	Cache *Cache
+}
+
+// Cache holds a mutex.
+//
+//stringergen:method=codegen
+type Cache struct {
+	Items []int
+}
+
+type (
+	//stringergen:method=logfmt
+	Node struct {
+		Name string
+	}
+
+	//stringergen:skip
+	Internal struct{}
+)
+
+//stringergen:skip
+type Color int
+
+const Red Color = 0
+`
+	o := parseOutput(t, src, "json")
+	assert.Equal(t, []string{"Request", "Cache", "Node"}, o.structNames)
+	assert.Equal(t, map[string]string{"Cache": "codegen", "Node": "logfmt"}, o.typeMethods)
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	for _, want := range []string{
+		"\"encoding/json\"",
+		"\"github.com/chasemao/stringergen/strgen\"",
+		"func (r *Request) String() string {\n\tif r == nil {\n\t\treturn \"<nil>\"\n\t}\n\tv, err := json.Marshal(r)",
+		"func (c *Cache) String() string {",
+		"sb.WriteString(`{\"Items\":`)",
+		"func (n *Node) String() string {",
+		"sb.WriteString(`Name=`)",
+	} {
+		assert.Contains(t, string(res), want)
+	}
+	assert.NotContains(t, string(res), "Internal")
+	// the flags are restored
+	assert.Equal(t, "json", o.method)
+	assert.Len(t, o.structNames, 3)
+
+	o = parseOutput(t, src, "codegen")
+	o.indent = "  "
+	_, err = o.gen()
+	assert.EqualError(t, err, "Node of directive //stringergen:method=logfmt: indent is not supported by method logfmt")
+}
+
+func TestGenDirectiveRedact(t *testing.T) {
+	src := `
+package main
+
+type Creds struct {
+	User     string
+	Password string ` + "`stringer:\"redact\"`" + `
+}
+
+//stringergen:method=json
+type Outer struct {
+	Creds
+}
+
+//stringergen:method=slog
+type Login struct {
+	Backups []Creds
+}
+`
+	// Creds is written by codegen, which redacts it, but not when another group marshals or logs it
+	o := parseOutput(t, src, "codegen")
+	_, err := o.gen()
+	assert.EqualError(t, err, "field Outer.Creds reaches redacted fields, which are not supported by method json, use method codegen instead")
+
+	o = parseOutput(t, strings.Replace(src, "\tCreds\n", "\tName string\n", 1), "codegen")
+	_, err = o.gen()
+	assert.EqualError(t, err, "elements of Login.Backups have redacted fields, which are not redacted in a slice, array or map by method slog, use method codegen instead")
+
+	// -redact is matched in every type too
+	untagged := strings.Replace(src, " `stringer:\"redact\"`", "", 1)
+	o = parseOutput(t, untagged, "codegen")
+	_, err = o.gen()
+	assert.NoError(t, err)
+	o = parseOutput(t, untagged, "codegen")
+	o.redact = []*regexp.Regexp{regexp.MustCompile("^Password$")}
+	_, err = o.gen()
+	assert.EqualError(t, err, "field Outer.Creds reaches redacted fields, which are not supported by method json, use method codegen instead")
+}
+
+func TestGenForeignRedact(t *testing.T) {
+	src := `
+package main
+
+type A struct {
+	B  B
+	PB *B
+}
+
+//stringergen:method=logfmt
+type B struct {
+	Password string ` + "`stringer:\"redact\"`" + `
+}
+`
+	// codegen writes B of another group by encoding/json, which does not redact it
+	o := parseOutput(t, src, "codegen")
+	_, err := o.gen()
+	assert.EqualError(t, err, "field A.B reaches redacted fields of B, which method codegen writes like a type of another package without redacting them")
+
+	// so it does for a type with its own method in the package
+	o = parseOutput(t, strings.Replace(src, "//stringergen:method=logfmt\n", "", 1), "codegen")
+	o.skipDeclared(map[string]map[string]bool{"B": {"String": true}})
+	_, err = o.gen()
+	assert.EqualError(t, err, "field A.B reaches redacted fields of B, which method codegen writes like a type of another package without redacting them")
+
+	// and for an anonymous struct
+	o = parseOutput(t, `
+package main
+
+type A struct {
+	S []struct {
+		Password string `+"`stringer:\"redact\"`"+`
+	}
+}
+`, "codegen")
+	_, err = o.gen()
+	assert.EqualError(t, err, "field A.S reaches redacted fields of struct{...}, which method codegen writes like a type of another package without redacting them")
+
+	// a group writing B by itself redacts it
+	o = parseOutput(t, strings.Replace(src, "\tB  B\n\tPB *B\n", "\tName string\n", 1), "codegen")
+	_, err = o.gen()
+	assert.NoError(t, err)
+}
+
+func TestGenTemplateDirective(t *testing.T) {
+	src := `
+package main
+
+type Request struct {
+	ID int
+}
+
+//stringergen:method=template
+type Node struct {
+	Name string
+}
+`
+	path := filepath.Join(t.TempDir(), "node.tmpl")
+	if err := os.WriteFile(path, []byte(`return "node " + {{.Receiver}}.Name`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseTemplate("json", path)
+	if err != nil {
+		t.Fatalf("parseTemplate() error: %v", err)
+	}
+	o := parseOutput(t, src, "json")
+	o.template = tmpl
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), "v, err := json.Marshal(r)")
+	assert.Contains(t, string(res), "return \"node \" + n.Name")
+
+	o = parseOutput(t, src, "json")
+	_, err = o.gen()
+	assert.EqualError(t, err, "Node of directive //stringergen:method=template: method template needs -template flag")
+}
+
+func TestParseFileSkipEnum(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", `
+package main
+
+//stringergen:skip
+type Color int
+
+type Size int
+
+const (
+	Red Color = 0
+	Big Size  = 0
+)
+`, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parser.ParseFile() error: %v", err)
+	}
+	o, err := parseFile(file, nil, &genOptions{enum: true})
+	assert.NoError(t, err)
+	if assert.Len(t, o.enums, 1) {
+		assert.Equal(t, "Size", o.enums[0].name)
+	}
+}
@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+)
+
+// enum is a named integer type with constants declared in the file, like type Status int.
+type enum struct {
+	name     string
+	unsigned bool
+	// consts are the names of constants with distinct values in declaration order
+	consts []string
+}
+
+// parseEnums returns the named integer types of file which have constants in it.
+// Constant values are evaluated by go/types, so that iota and implicit repetition are handled,
+// of constants with the same value only the first one is kept.
+func parseEnums(file *ast.File, exclRes []*regexp.Regexp) []*enum {
+	var enums []*enum
+	byName := make(map[string]*enum)
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || ts.Assign.IsValid() || ts.TypeParams != nil {
+				continue
+			}
+			id, ok := ts.Type.(*ast.Ident)
+			if !ok || (basicKind(id.Name) != "int" && basicKind(id.Name) != "uint") {
+				continue
+			}
+			name := ts.Name.Name
+			if matchExcl(name, exclRes) {
+				d.Printf("EXCLUDE ENUM: %s", name)
+				continue
+			}
+			e := &enum{name: name, unsigned: basicKind(id.Name) == "uint"}
+			enums = append(enums, e)
+			byName[name] = e
+		}
+	}
+	if len(enums) == 0 {
+		return nil
+	}
+
+	// imports are not needed to evaluate the constants of local types, errors of them are ignored
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	conf := &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			return nil, errors.New("not imported")
+		}),
+		Error: func(err error) {},
+	}
+	// the file set of file is not kept, positions are only used by error messages which are ignored
+	fset := token.NewFileSet()
+	fset.AddFile("", int(file.FileStart), int(file.FileEnd-file.FileStart))
+	pkg, _ := conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	seen := make(map[string]bool)
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			for _, id := range spec.(*ast.ValueSpec).Names {
+				c, ok := info.Defs[id].(*types.Const)
+				if !ok || id.Name == "_" || c.Val().Kind() != constant.Int {
+					continue
+				}
+				named, ok := c.Type().(*types.Named)
+				if !ok || named.Obj().Pkg() != pkg {
+					continue
+				}
+				e, ok := byName[named.Obj().Name()]
+				if !ok {
+					continue
+				}
+				key := e.name + "=" + c.Val().ExactString()
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				e.consts = append(e.consts, id.Name)
+			}
+		}
+	}
+
+	var res []*enum
+	for _, e := range enums {
+		if len(e.consts) > 0 {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// enumString writes a String method which returns the constant name of the value without -trimprefix,
+// other values are written like Status(7).
+func (o *output) enumString(e *enum) {
+	n := strings.ToLower(e.name[0:1])
+	o.addln("// String Used in fmt to generate string")
+	o.addln(fmt.Sprintf("func (%s %s) String() string {", n, e.name))
+	o.addln(fmt.Sprintf("switch %s {", n))
+	for _, c := range e.consts {
+		o.addln(fmt.Sprintf("case %s:", c))
+		o.addln(fmt.Sprintf("return %q", strings.TrimPrefix(c, o.trimPrefix)))
+	}
+	o.addln("}")
+	if e.unsigned {
+		o.addln(fmt.Sprintf(`return "%s(" + strconv.FormatUint(uint64(%s), 10) + ")"`, e.name, n))
+	} else {
+		o.addln(fmt.Sprintf(`return "%s(" + strconv.FormatInt(int64(%s), 10) + ")"`, e.name, n))
+	}
+	o.addln("}")
+}
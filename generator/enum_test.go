@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"go/parser"
+	"go/token"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnums(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", `
+package main
+
+import "time"
+
+type Status int
+
+const (
+	StatusUnknown Status = iota
+	StatusActive
+	_
+	StatusDeleted
+	StatusDefault = StatusUnknown
+)
+
+type Flag uint8
+
+const (
+	FlagA Flag = 1 << iota
+	FlagB
+)
+
+const Timeout = time.Second
+
+type Skipped int
+
+type Excluded int
+
+const ExcludedA Excluded = 1
+
+type Alias = int
+
+const AliasA Alias = 1
+`, parser.AllErrors)
+	if err != nil {
+		t.Fatalf("parser.ParseFile() error: %v", err)
+	}
+
+	enums := parseEnums(file, []*regexp.Regexp{regexp.MustCompile("^Excluded$")})
+
+	assert.Equal(t, []*enum{
+		{name: "Status", consts: []string{"StatusUnknown", "StatusActive", "StatusDeleted"}},
+		{name: "Flag", unsigned: true, consts: []string{"FlagA", "FlagB"}},
+	}, enums)
+}
+
+func TestEnumString(t *testing.T) {
+	o := &output{trimPrefix: "Status"}
+
+	o.enumString(&enum{name: "Status", consts: []string{"StatusUnknown", "StatusActive"}})
+
+	expected := `// String Used in fmt to generate string
+func (s Status) String() string {
+switch s {
+case StatusUnknown:
+return "Unknown"
+case StatusActive:
+return "Active"
+}
+return "Status(" + strconv.FormatInt(int64(s), 10) + ")"
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+
+	o = &output{}
+	o.enumString(&enum{name: "Flag", unsigned: true, consts: []string{"FlagA"}})
+	assert.Contains(t, o.buf.String(), `return "Flag(" + strconv.FormatUint(uint64(f), 10) + ")"`)
+}
+
+func TestGenEnum(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", `
+package main
+
+type Status int
+
+const (
+	StatusActive Status = iota + 1
+	StatusDeleted
+)
+`, parser.AllErrors)
+	if err != nil {
+		t.Fatalf("parser.ParseFile() error: %v", err)
+	}
+	o, err := parseFile(file, nil, &genOptions{method: "codegen", enum: true})
+	if err != nil {
+		t.Fatalf("parseFile() error: %v", err)
+	}
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	expected := `package main
+
+import (
+	"strconv"
+)
+
+// String Used in fmt to generate string
+func (s Status) String() string {
+	switch s {
+	case StatusActive:
+		return "StatusActive"
+	case StatusDeleted:
+		return "StatusDeleted"
+	}
+	return "Status(" + strconv.FormatInt(int64(s), 10) + ")"
+}
+`
+	assert.Equal(t, expected, string(res))
+}
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// formatMethod writes a Format method, %v prints the fields in one line, %+v adds field names,
+// %#v calls GoString, %s and %q call String. The fmt.State is named st, which a one letter receiver
+// can not clash with.
+func (o *output) formatMethod(name string) {
+	n := strings.ToLower(name[0:1])
+	var verbs, plusVerbs, args []string
+	for _, f := range o.structFields(o.structType(name)) {
+		if p := placeholder(f.Type); p != "" && f.Redact == "" {
+			// go vet reports a func value passed to Fprintf, which would print an address only
+			verbs = append(verbs, p)
+			plusVerbs = append(plusVerbs, f.Key+":"+p)
+			continue
+		}
+		verbs = append(verbs, "%v")
+		plusVerbs = append(plusVerbs, f.Key+":%+v")
+		arg := sel(n, f.Name)
+		if f.Redact != "" {
+			arg = f.redaction(arg)
+		} else if id, ok := f.Type.(*ast.Ident); ok && o.isStruct(id.Name) {
+			// Format is defined on the pointer
+			arg = "&" + arg
+		}
+		args = append(args, arg)
+	}
+	argList := ""
+	if len(args) > 0 {
+		argList = ", " + strings.Join(args, ", ")
+	}
+
+	o.addln("// Format Used in fmt to generate string according to verb and flags")
+	o.addln(fmt.Sprintf("func (%s) Format(st fmt.State, verb rune) {", o.recv(name)))
+	o.addln("if verb == 'v' && st.Flag('#') {")
+	o.addln(fmt.Sprintf("_, _ = io.WriteString(st, %s.GoString())", n))
+	o.addln("return")
+	o.addln("}")
+	if !o.valueReceiver() {
+		o.addln(fmt.Sprintf("if %s == nil {", n))
+		o.addln(`_, _ = io.WriteString(st, "<nil>")`)
+		o.addln("return")
+		o.addln("}")
+	}
+	o.addln("switch verb {")
+	o.addln("case 'v':")
+	o.addln("if st.Flag('+') {")
+	o.addln(fmt.Sprintf("fmt.Fprintf(st, %q%s)", "{"+strings.Join(plusVerbs, " ")+"}", argList))
+	o.addln("} else {")
+	o.addln(fmt.Sprintf("fmt.Fprintf(st, %q%s)", "{"+strings.Join(verbs, " ")+"}", argList))
+	o.addln("}")
+	o.addln("case 's':")
+	o.addln(fmt.Sprintf("_, _ = io.WriteString(st, %s.String())", n))
+	o.addln("case 'q':")
+	o.addln(fmt.Sprintf("_, _ = io.WriteString(st, strconv.Quote(%s.String()))", n))
+	o.addln("default:")
+	if o.isGeneric(name) {
+		// the type arguments are known at run time only
+		o.addln(fmt.Sprintf(`fmt.Fprintf(st, "%%%%!%%c(%%T=%%s)", verb, %s, %s.String())`, n, n))
+	} else {
+		typ := o.pkg + "." + name
+		if !o.valueReceiver() {
+			typ = "*" + typ
+		}
+		o.addln(fmt.Sprintf("fmt.Fprintf(st, %q, verb, %s.String())", "%%!%c("+typ+"=%s)", n))
+	}
+	o.addln("}")
+	o.addln("}")
+}
@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMethod(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+	Field2 Sub
+}
+
+type Sub struct{}
+`, "json")
+
+	o.formatMethod("MyStruct")
+
+	expected := `// Format Used in fmt to generate string according to verb and flags
+func (m *MyStruct) Format(st fmt.State, verb rune) {
+if verb == 'v' && st.Flag('#') {
+_, _ = io.WriteString(st, m.GoString())
+return
+}
+if m == nil {
+_, _ = io.WriteString(st, "<nil>")
+return
+}
+switch verb {
+case 'v':
+if st.Flag('+') {
+fmt.Fprintf(st, "{Field1:%+v Field2:%+v}", m.Field1, &m.Field2)
+} else {
+fmt.Fprintf(st, "{%v %v}", m.Field1, &m.Field2)
+}
+case 's':
+_, _ = io.WriteString(st, m.String())
+case 'q':
+_, _ = io.WriteString(st, strconv.Quote(m.String()))
+default:
+fmt.Fprintf(st, "%%!%c(*main.MyStruct=%s)", verb, m.String())
+}
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenFormatter(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+}
+`, "retype")
+	o.formatter = true
+
+	got, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(got), "func (m *MyStruct) GoString() string {")
+	assert.Contains(t, string(got), "func (m *MyStruct) Format(st fmt.State, verb rune) {")
+
+	o.method = "zap"
+	_, err = o.gen()
+	assert.Error(t, err)
+}
+
+func TestGenFormatterCompiles(t *testing.T) {
+	src := `
+package main
+
+type Foo struct {
+	Fn   func() error
+	Ch   chan int
+	Next *Foo
+}
+
+type Fields struct {
+	Foo Foo
+}
+`
+	for _, receiver := range []string{"pointer", "value"} {
+		t.Run(receiver, func(t *testing.T) {
+			o := parseOutput(t, src, "codegen")
+			o.receiver = receiver
+			o.formatter = true
+
+			res, err := o.gen()
+			assert.NoError(t, err)
+			typeCheck(t, src, res)
+			assert.Contains(t, string(res), `fmt.Fprintf(st, "{Fn:<func> Ch:<chan> Next:%+v}", f.Next)`)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// typeParams returns the type parameter list of name like [K comparable, V any], empty if name is not generic.
+func (o *output) typeParams(name string) string {
+	ts, ok := o.specs[name]
+	if !ok || ts.TypeParams == nil {
+		return ""
+	}
+	var params []string
+	for _, f := range ts.TypeParams.List {
+		var names []string
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		params = append(params, strings.Join(names, ", ")+" "+types.ExprString(f.Type))
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// typeArgs returns the type parameters of name as type arguments like [K, V], empty if name is not generic.
+func (o *output) typeArgs(name string) string {
+	ts, ok := o.specs[name]
+	if !ok || ts.TypeParams == nil {
+		return ""
+	}
+	var args []string
+	for _, f := range ts.TypeParams.List {
+		for _, n := range f.Names {
+			args = append(args, n.Name)
+		}
+	}
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
+// recvType returns the receiver type of the methods of name like Page[T].
+func (o *output) recvType(name string) string {
+	return name + o.typeArgs(name)
+}
+
+func (o *output) isGeneric(name string) bool {
+	return o.typeArgs(name) != ""
+}
+
+// generating sets name as the type whose methods are being generated,
+// its type parameters are not the types of this output even if they have the same name.
+func (o *output) generating(name string) {
+	o.params = make(map[string]bool)
+	ts, ok := o.specs[name]
+	if !ok || ts.TypeParams == nil {
+		return
+	}
+	for _, f := range ts.TypeParams.List {
+		for _, n := range f.Names {
+			o.params[n.Name] = true
+		}
+	}
+}
+
+// typeName returns the name of a type in this package like T or Page[T], empty for other types.
+func typeName(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return typeName(t.X)
+	case *ast.IndexListExpr:
+		return typeName(t.X)
+	}
+	return ""
+}
+
+// hasTypeParam reports whether typ refers to a type parameter of the type being generated.
+func (o *output) hasTypeParam(typ ast.Expr) bool {
+	found := false
+	ast.Inspect(typ, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			// pkg.T is not a type parameter
+			return false
+		case *ast.Ident:
+			found = found || o.params[x.Name]
+		}
+		return !found
+	})
+	return found
+}
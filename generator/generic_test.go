@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"go/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeParams(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type Page[T any] struct{}
+
+type Pair[K comparable, V fmt.Stringer] struct{}
+
+type Plain struct{}
+`, "")
+
+	assert.Equal(t, "[T any]", o.typeParams("Page"))
+	assert.Equal(t, "[T]", o.typeArgs("Page"))
+	assert.Equal(t, "[K comparable, V fmt.Stringer]", o.typeParams("Pair"))
+	assert.Equal(t, "Pair[K, V]", o.recvType("Pair"))
+	assert.Equal(t, "", o.typeParams("Plain"))
+	assert.Equal(t, "Plain", o.recvType("Plain"))
+}
+
+func TestHasTypeParam(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type Page[T any] struct{}
+`, "")
+	o.generating("Page")
+
+	for src, want := range map[string]bool{
+		"T":            true,
+		"[]*T":         true,
+		"map[string]T": true,
+		"int":          false,
+		"pkg.T":        false,
+	} {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatalf("parser.ParseExpr() error: %v", err)
+		}
+		assert.Equal(t, want, o.hasTypeParam(expr), src)
+	}
+}
+
+func TestGenGeneric(t *testing.T) {
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{
+			method: "json",
+			want:   []string{"func (p *Page[T]) String() string {"},
+		},
+		{
+			method: "retype",
+			want: []string{
+				"type PageTarget[T any] Page[T]",
+				`return fmt.Sprintf("%+v", (*PageTarget[T])(p))`,
+			},
+		},
+		{
+			method: "codegen",
+			want: []string{
+				"func (p *Page[T]) String() string {",
+				// T is a type parameter, not the struct T
+				"strgen.WriteJSON(sb, p.Item)",
+				"sb.WriteString(p.Next.String())",
+			},
+		},
+		{
+			method: "append",
+			want:   []string{"func (p *Page[T]) AppendString(dst []byte) []byte {"},
+		},
+		{
+			method: "logfmt",
+			want:   []string{"func (p *Page[T]) String() string {"},
+		},
+		{
+			method: "slog",
+			want:   []string{"func (p *Page[T]) LogValue() slog.Value {"},
+		},
+		{
+			method: "zap",
+			want:   []string{"func (p *Page[T]) MarshalLogObject(enc zapcore.ObjectEncoder) error {"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			o := parseOutput(t, `
+package main
+
+type Page[T any] struct {
+	Item  T
+	Items []T
+	Next  *Page[T]
+}
+
+type T struct{}
+`, tt.method)
+			o.structNames = o.structNames[:1]
+			o.formatter = tt.method != "slog" && tt.method != "zap"
+
+			res, err := o.gen()
+			assert.NoError(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, string(res), want)
+			}
+			if o.formatter {
+				assert.Contains(t, string(res), `fmt.Fprintf(st, "%%!%c(%T=%s)", verb, p, p.String())`)
+				assert.Contains(t, string(res), `sb.WriteString(fmt.Sprintf("&%T{", *p))`)
+				assert.Contains(t, string(res), `sb.WriteString(fmt.Sprintf("%T{", p.Items))`)
+			}
+		})
+	}
+}
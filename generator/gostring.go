@@ -0,0 +1,267 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// goStringMethod writes a GoString method which returns a Go composite literal of the struct,
+// nested pointers are expanded instead of printed as addresses like %#v does.
+func (o *output) goStringMethod(name string) {
+	n := strings.ToLower(name[0:1])
+	o.addln("// GoString Used in fmt to generate Go syntax for %#v")
+	o.addln(fmt.Sprintf("func (%s) GoString() string {", o.recv(name)))
+	if o.isGeneric(name) {
+		// the type arguments are known at run time only
+		o.nilGuard(n, fmt.Sprintf(`fmt.Sprintf("(%%T)(nil)", %s)`, n))
+	} else {
+		o.nilGuard(n, goLiteral(fmt.Sprintf("(*%s.%s)(nil)", o.pkg, name)))
+	}
+	o.addln("sb := &strings.Builder{}")
+	g := &gostringgen{body: body{o: o}}
+	// the literal of a value receiver is a value, not a pointer
+	amp := "&"
+	if o.valueReceiver() {
+		amp = ""
+	}
+	if o.isGeneric(name) {
+		g.stmt(fmt.Sprintf(`sb.WriteString(fmt.Sprintf("%s%%T{", %s))`, amp, o.deref(n)))
+	} else {
+		g.writeLit(fmt.Sprintf("%s%s.%s{", amp, o.pkg, name))
+	}
+	var fields []*Field
+	for _, f := range o.structFields(o.structType(name)) {
+		// a field left out of the literal is the zero value, so a redacted one is not shown
+		if f.Redact == "" {
+			fields = append(fields, f)
+		}
+	}
+	sep := &separator{b: &g.body, sep: ", "}
+	for i, f := range fields {
+		cond := ""
+		if f.OmitEmpty {
+			cond = o.notEmpty(sel(n, f.Name), f.Type)
+		}
+		sep.field(cond, i < len(fields)-1, func() {
+			g.writeLit(f.Name + ": ")
+			g.value(sel(n, f.Name), f.Type)
+		})
+	}
+	g.writeLit("}")
+	g.flush()
+	o.addln("return sb.String()")
+	o.addln("}")
+}
+
+// gostringgen writes the body of a GoString method.
+type gostringgen struct {
+	body
+	depth int
+}
+
+// value writes the Go literal of expr whose type is typ.
+func (g *gostringgen) value(expr string, typ ast.Expr) {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		g.value(expr, t.X)
+		return
+	case *ast.Ident:
+		if g.o.generatesGoString(t.Name) {
+			g.nested(expr)
+			return
+		}
+		if t.Name == "any" {
+			g.nilable(expr, func() {
+				g.stmt(fmt.Sprintf(`sb.WriteString(fmt.Sprintf("%%#v", %s))`, expr))
+			})
+			return
+		}
+		switch basicKind(t.Name) {
+		case "string":
+			g.stmt(fmt.Sprintf("sb.WriteString(strconv.Quote(%s))", expr))
+			return
+		case "bool":
+			g.stmt(fmt.Sprintf("sb.WriteString(strconv.FormatBool(%s))", expr))
+			return
+		case "int":
+			g.stmt(fmt.Sprintf("sb.WriteString(strconv.FormatInt(int64(%s), 10))", expr))
+			return
+		case "uint":
+			g.stmt(fmt.Sprintf("sb.WriteString(strconv.FormatUint(uint64(%s), 10))", expr))
+			return
+		case "float":
+			// %#v prints NaN and +Inf, which are not Go expressions
+			bits := "64"
+			if t.Name == "float32" {
+				bits = "32"
+			}
+			g.stmt(fmt.Sprintf("sb.WriteString(strgen.GoFloat(float64(%s), %s))", expr, bits))
+			return
+		case "error":
+			g.stmt(fmt.Sprintf("if %s == nil {", expr))
+			g.writeLit("nil")
+			g.stmt("} else {")
+			g.writeLit("errors.New(")
+			g.stmt(fmt.Sprintf("sb.WriteString(strconv.Quote(%s))", sel(expr, "Error()")))
+			g.writeLit(")")
+			g.stmt("}")
+			return
+		}
+	case *ast.StarExpr:
+		g.stmt(fmt.Sprintf("if %s == nil {", expr))
+		g.writeLit("nil")
+		g.stmt("} else {")
+		if g.o.generatesGoString(typeName(t.X)) {
+			if g.o.valueReceiver() {
+				// GoString of T returns pkg.T{...}, add the & for a pointer
+				g.writeLit("&")
+			}
+			g.stmt(fmt.Sprintf("sb.WriteString(%s)", sel(expr, "GoString()")))
+		} else if id, ok := t.X.(*ast.Ident); ok && basicKind(id.Name) != "" && basicKind(id.Name) != "error" {
+			// the address of a literal can not be taken, wrap it in a function
+			g.writeLit(fmt.Sprintf("func() *%s { v := %s(", id.Name, id.Name))
+			g.value("*"+expr, id)
+			g.writeLit("); return &v }()")
+		} else {
+			g.stmt(fmt.Sprintf(`sb.WriteString(fmt.Sprintf("%%#v", %s))`, expr))
+		}
+		g.stmt("}")
+		return
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		if g.o.generatesGoString(typeName(t)) {
+			g.nested(expr)
+			return
+		}
+	case *ast.FuncType, *ast.ChanType:
+		g.nilable(expr, func() {
+			// a func or channel value has no literal, the address of %#v could not be pasted
+			g.writeLit("nil /* non-nil " + types.ExprString(t) + " */")
+		})
+		return
+	case *ast.InterfaceType:
+		g.nilable(expr, func() {
+			g.stmt(fmt.Sprintf(`sb.WriteString(fmt.Sprintf("%%#v", %s))`, expr))
+		})
+		return
+	case *ast.ArrayType:
+		if t.Len == nil {
+			g.stmt(fmt.Sprintf("if %s == nil {", expr))
+			g.writeLit("nil")
+			g.stmt("} else {")
+			defer g.stmt("}")
+		}
+		i, v := fmt.Sprintf("i%d", g.depth), fmt.Sprintf("v%d", g.depth)
+		g.depth++
+		defer func() { g.depth-- }()
+		g.literalType(expr, t)
+		g.stmt(fmt.Sprintf("for %s, %s := range %s {", i, v, expr))
+		g.stmt(fmt.Sprintf("if %s > 0 {", i))
+		g.writeLit(", ")
+		g.stmt("}")
+		g.value(v, t.Elt)
+		g.stmt("}")
+		g.writeLit("}")
+		return
+	case *ast.MapType:
+		k, v, first := fmt.Sprintf("k%d", g.depth), fmt.Sprintf("v%d", g.depth), fmt.Sprintf("first%d", g.depth)
+		g.depth++
+		defer func() { g.depth-- }()
+		g.stmt(fmt.Sprintf("if %s == nil {", expr))
+		g.writeLit("nil")
+		g.stmt("} else {")
+		g.literalType(expr, t)
+		g.stmt(fmt.Sprintf("%s := true", first))
+		if keys := sortedKeys(t.Key); keys != "" {
+			// keys are sorted like codegen does, so that the literal is stable
+			g.stmt(fmt.Sprintf("for _, %s := range strgen.%s(%s) {", k, keys, expr))
+			g.stmt(fmt.Sprintf("%s := %s", v, index(expr, k)))
+		} else {
+			g.stmt(fmt.Sprintf("for %s, %s := range %s {", k, v, expr))
+		}
+		g.stmt(fmt.Sprintf("if !%s {", first))
+		g.writeLit(", ")
+		g.stmt("}")
+		g.stmt(fmt.Sprintf("%s = false", first))
+		g.value(k, t.Key)
+		g.writeLit(": ")
+		g.value(v, t.Value)
+		g.stmt("}")
+		g.writeLit("}")
+		g.stmt("}")
+		return
+	}
+	// type parameters and types from other packages, time.Time has its own GoString
+	g.stmt(fmt.Sprintf(`sb.WriteString(fmt.Sprintf("%%#v", %s))`, expr))
+}
+
+// generatesGoString reports whether GoString of name is generated, so that its literal is known,
+// a struct with its own GoString in the package is printed by %#v like other types.
+func (o *output) generatesGoString(name string) bool {
+	return o.isStruct(name) && o.declaredMethod(name, "GoString") == ""
+}
+
+// nested writes the literal of the struct value expr by its GoString.
+func (g *gostringgen) nested(expr string) {
+	if g.o.valueReceiver() {
+		g.stmt(fmt.Sprintf("sb.WriteString(%s)", sel(expr, "GoString()")))
+		return
+	}
+	// GoString of *T returns &pkg.T{...}, drop the & for a value
+	g.stmt(fmt.Sprintf("sb.WriteString(%s[1:])", sel(expr, "GoString()")))
+}
+
+// nilable writes nil when expr is nil, otherwise the literal written by notNil.
+func (g *gostringgen) nilable(expr string, notNil func()) {
+	g.stmt(fmt.Sprintf("if %s == nil {", expr))
+	g.writeLit("nil")
+	g.stmt("} else {")
+	notNil()
+	g.stmt("}")
+}
+
+// literalType writes the type of the slice, array or map literal of expr, which is known at run time only
+// if it refers to a type parameter.
+func (g *gostringgen) literalType(expr string, typ ast.Expr) {
+	if g.o.hasTypeParam(typ) {
+		g.stmt(fmt.Sprintf(`sb.WriteString(fmt.Sprintf("%%T{", %s))`, expr))
+		return
+	}
+	g.writeLit(g.o.goType(typ) + "{")
+}
+
+// goType returns the type expression typ as seen from another package,
+// types declared in this package are qualified by the package name.
+func (o *output) goType(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		if isPredeclared(t.Name) {
+			return t.Name
+		}
+		return o.pkg + "." + t.Name
+	case *ast.ParenExpr:
+		return "(" + o.goType(t.X) + ")"
+	case *ast.StarExpr:
+		return "*" + o.goType(t.X)
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + o.goType(t.Elt)
+		}
+		return "[" + types.ExprString(t.Len) + "]" + o.goType(t.Elt)
+	case *ast.MapType:
+		return "map[" + o.goType(t.Key) + "]" + o.goType(t.Value)
+	}
+	return types.ExprString(typ)
+}
+
+func isPredeclared(name string) bool {
+	if basicKind(name) != "" {
+		return true
+	}
+	switch name {
+	case "any", "complex64", "complex128":
+		return true
+	}
+	return false
+}
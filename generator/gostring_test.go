@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoStringMethod(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+	Field2 *Sub
+	Field3 []Sub
+	Field4 *int64
+}
+
+type Sub struct{}
+`, "json")
+
+	o.goStringMethod("MyStruct")
+
+	expected := "// GoString Used in fmt to generate Go syntax for %#v\n" +
+		"func (m *MyStruct) GoString() string {\n" +
+		"if m == nil {\n" +
+		"return `(*main.MyStruct)(nil)`\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"sb.WriteString(`&main.MyStruct{Field1: `)\n" +
+		"sb.WriteString(strconv.Quote(m.Field1))\n" +
+		"sb.WriteString(`, Field2: `)\n" +
+		"if m.Field2 == nil {\n" +
+		"sb.WriteString(`nil`)\n" +
+		"} else {\n" +
+		"sb.WriteString(m.Field2.GoString())\n" +
+		"}\n" +
+		"sb.WriteString(`, Field3: `)\n" +
+		"if m.Field3 == nil {\n" +
+		"sb.WriteString(`nil`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`[]main.Sub{`)\n" +
+		"for i0, v0 := range m.Field3 {\n" +
+		"if i0 > 0 {\n" +
+		"sb.WriteString(`, `)\n" +
+		"}\n" +
+		"sb.WriteString(v0.GoString()[1:])\n" +
+		"}\n" +
+		"sb.WriteString(`}`)\n" +
+		"}\n" +
+		"sb.WriteString(`, Field4: `)\n" +
+		"if m.Field4 == nil {\n" +
+		"sb.WriteString(`nil`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`func() *int64 { v := int64(`)\n" +
+		"sb.WriteString(strconv.FormatInt(int64(*m.Field4), 10))\n" +
+		"sb.WriteString(`); return &v }()`)\n" +
+		"}\n" +
+		"sb.WriteString(`}`)\n" +
+		"return sb.String()\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGoStringNilable(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Fn  func() error
+	Ch  chan int
+	Any interface{}
+	Val any
+}
+`, "json")
+
+	o.goStringMethod("MyStruct")
+
+	expected := "// GoString Used in fmt to generate Go syntax for %#v\n" +
+		"func (m *MyStruct) GoString() string {\n" +
+		"if m == nil {\n" +
+		"return `(*main.MyStruct)(nil)`\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"sb.WriteString(`&main.MyStruct{Fn: `)\n" +
+		"if m.Fn == nil {\n" +
+		"sb.WriteString(`nil`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`nil /* non-nil func() error */`)\n" +
+		"}\n" +
+		"sb.WriteString(`, Ch: `)\n" +
+		"if m.Ch == nil {\n" +
+		"sb.WriteString(`nil`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`nil /* non-nil chan int */`)\n" +
+		"}\n" +
+		"sb.WriteString(`, Any: `)\n" +
+		"if m.Any == nil {\n" +
+		"sb.WriteString(`nil`)\n" +
+		"} else {\n" +
+		"sb.WriteString(fmt.Sprintf(\"%#v\", m.Any))\n" +
+		"}\n" +
+		"sb.WriteString(`, Val: `)\n" +
+		"if m.Val == nil {\n" +
+		"sb.WriteString(`nil`)\n" +
+		"} else {\n" +
+		"sb.WriteString(fmt.Sprintf(\"%#v\", m.Val))\n" +
+		"}\n" +
+		"sb.WriteString(`}`)\n" +
+		"return sb.String()\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenGoStringRuns(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	A float64
+	B float32
+	C []float64
+	D func()
+	E chan int
+}
+`
+	o := parseOutput(t, src, "codegen")
+	o.goString = true
+	res, err := o.gen()
+	assert.NoError(t, err)
+	out := run(t, src, res, `
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+func main() {
+	fmt.Println((&MyStruct{A: math.NaN(), B: float32(math.Inf(1)), C: []float64{math.Inf(-1), 1.5}, D: func() {}}).GoString())
+}
+`)
+	assert.Equal(t, "&main.MyStruct{A: math.NaN(), B: float32(math.Inf(1)), C: []float64{math.Inf(-1), 1.5}, D: nil /* non-nil func() */, E: nil}\n", out)
+	// it can be pasted
+	_, err = parser.ParseExpr(out)
+	assert.NoError(t, err)
+}
+
+func TestGenGoStringDeclaredRuns(t *testing.T) {
+	src := `
+package main
+
+type Sub struct {
+	X int
+}
+
+type Top struct {
+	S  Sub
+	P  *Sub
+	Ss []Sub
+	M  map[string]int
+	N  map[int]bool
+}
+`
+	o := parseOutput(t, src, "codegen")
+	o.goString = true
+	o.skipDeclared(map[string]map[string]bool{"Sub": {"GoString": true}})
+	res, err := o.gen()
+	assert.NoError(t, err)
+	out := run(t, src, res, `
+package main
+
+import "fmt"
+
+func (s *Sub) GoString() string { return "CUSTOM" }
+
+func main() {
+	top := &Top{S: Sub{X: 1}, P: &Sub{X: 2}, Ss: []Sub{{X: 3}}, M: map[string]int{"b": 2, "a": 1, "c": 3}, N: map[int]bool{10: true, 9: false, 1: true}}
+	for i := 0; i < 3; i++ {
+		fmt.Println(top.GoString())
+	}
+}
+`)
+	// a GoString of the package is not cut like the generated one, the keys are sorted like codegen does
+	want := `&main.Top{S: main.Sub{X:1}, P: CUSTOM, Ss: []main.Sub{main.Sub{X:3}}, M: map[string]int{"a": 1, "b": 2, "c": 3}, N: map[int]bool{1: true, 10: true, 9: false}}` + "\n"
+	assert.Equal(t, want+want+want, out)
+}
+
+func TestGenGoString(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A map[string][]*MyStruct
+	B error
+	C float64
+	D Status
+}
+`, "fmt")
+	o.goString = true
+
+	got, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(got), "func (m *MyStruct) String() string {")
+	assert.Contains(t, string(got), "func (m *MyStruct) GoString() string {")
+}
+
+func TestGoType(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A map[string][]*MyStruct
+	B [2]time.Time
+	C interface{}
+}
+`, "json")
+
+	var got []string
+	for _, f := range o.structFields(o.specs["MyStruct"].Type.(*ast.StructType)) {
+		got = append(got, o.goType(f.Type))
+	}
+	assert.Equal(t, []string{"map[string][]*main.MyStruct", "[2]time.Time", "interface{}"}, got)
+}
+
+func TestGoStringGenericValueReceiver(t *testing.T) {
+	src := `
+package main
+
+type Holder struct {
+	Pair *Pair[string, int]
+	Val  Pair[string, int]
+}
+
+type Pair[K comparable, V any] struct {
+	M    map[K]*Sub
+	Next *Pair[K, V]
+}
+
+type Sub struct {
+	N int
+}
+`
+	o := parseOutput(t, src, "codegen")
+	o.receiver = "value"
+	o.goString = true
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	typeCheck(t, src, res)
+	// GoString of a value receiver returns a value, the & of pointers is written before it
+	assert.Contains(t, string(res), "sb.WriteString(`&`)\n\t\tsb.WriteString(h.Pair.GoString())")
+	assert.Contains(t, string(res), "sb.WriteString(`, Val: `)\n\tsb.WriteString(h.Val.GoString())")
+	assert.Contains(t, string(res), "sb.WriteString(`&`)\n\t\tsb.WriteString(p.Next.GoString())")
+	// the map type is known at run time only, its values are written one by one
+	assert.Contains(t, string(res), "sb.WriteString(fmt.Sprintf(\"%T{\", p.M))")
+	assert.Contains(t, string(res), "sb.WriteString(`&`)\n\t\t\t\tsb.WriteString(v0.GoString())")
+}
@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// hazard returns a method declared on type name which would be called by its String method of -method flag
+// instead of reading the fields, like MarshalJSON by json.Marshal. Such methods often call String in turn,
+// which would never return. It returns empty if there is none.
+func (o *output) hazard(name string) string {
+	caps := o.caps()
+	calls := caps.Calls
+	if caps.Fallback && o.fallback == "fmt" {
+		// fmt calls Format and Error before String
+		calls = append(append([]string(nil), calls...), "Format", "Error")
+	}
+	return o.declaredMethod(name, calls...)
+}
+
+// targetType writes the type without the methods of name which is printed by String methods.
+func (o *output) targetType(name string) {
+	o.addln(fmt.Sprintf("// %sTarget has the same fields as %s without its methods, so String does not call itself", name, name))
+	o.addln(fmt.Sprintf("type %sTarget%s %s", name, o.typeParams(name), o.recvType(name)))
+	o.addln("")
+}
+
+// target returns the receiver of name converted to the type written by targetType.
+func (o *output) target(name string) string {
+	n := strings.ToLower(name[0:1])
+	if o.valueReceiver() {
+		return fmt.Sprintf("%sTarget%s(%s)", name, o.typeArgs(name), n)
+	}
+	return fmt.Sprintf("(*%sTarget%s)(%s)", name, o.typeArgs(name), n)
+}
+
+// checkFallback returns an error if -fallback=fmt may never return. %+v of the fallback calls String of the nested
+// pointers, which fails to marshal a cycle of pointers again and falls back to %+v again, so it is not supported
+// for types referring to themselves.
+func (o *output) checkFallback() error {
+	if o.fallback != "fmt" || !o.caps().Fallback {
+		return nil
+	}
+	for _, name := range o.structNames {
+		if o.refers(name, name, make(map[string]bool)) {
+			return fmt.Errorf("fallback fmt is not supported for %s, which refers to itself, %%+v would never return on a cycle of pointers, use method codegen with -cycle instead", name)
+		}
+	}
+	return nil
+}
+
+// refers reports whether the fields or elements of type from refer to type to through the types in this output,
+// seen holds the types visited.
+func (o *output) refers(from string, to string, seen map[string]bool) bool {
+	if seen[from] {
+		return false
+	}
+	seen[from] = true
+	typ := o.underlying(from)
+	if typ == nil {
+		return false
+	}
+	exprs := []ast.Expr{typ}
+	if st, ok := typ.(*ast.StructType); ok {
+		exprs = nil
+		for _, f := range st.Fields.List {
+			exprs = append(exprs, f.Type)
+		}
+	}
+	found := false
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.SelectorExpr:
+				// pkg.T is not in this output
+				return false
+			case *ast.Ident:
+				if _, ok := o.specs[x.Name]; ok && (x.Name == to || o.refers(x.Name, to, seen)) {
+					found = true
+				}
+			}
+			return !found
+		})
+	}
+	return found
+}
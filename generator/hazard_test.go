@@ -0,0 +1,177 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHazard(t *testing.T) {
+	tests := []struct {
+		method   string
+		fallback string
+		declared []string
+		want     string
+	}{
+		{method: "json", declared: []string{"MarshalJSON"}, want: "MarshalJSON"},
+		{method: "jsoniter", declared: []string{"MarshalText"}, want: "MarshalText"},
+		{method: "json", declared: []string{"Format"}},
+		{method: "json", fallback: "fmt", declared: []string{"Error"}, want: "Error"},
+		{method: "fmt", declared: []string{"Format"}, want: "Format"},
+		{method: "fmt", declared: []string{"MarshalJSON"}},
+		{method: "template", declared: []string{"MarshalText"}, want: "MarshalText"},
+		{method: "codegen", declared: []string{"MarshalJSON", "Format"}},
+		{method: "retype", declared: []string{"Format"}},
+	}
+
+	for _, tt := range tests {
+		o := &output{method: tt.method, fallback: tt.fallback, declared: map[string]map[string]bool{"User": {}}}
+		for _, m := range tt.declared {
+			o.declared["User"][m] = true
+		}
+		assert.Equal(t, tt.want, o.hazard("User"), "%s %v", tt.method, tt.declared)
+	}
+}
+
+func TestGenHazard(t *testing.T) {
+	tests := []struct {
+		method   string
+		receiver string
+		want     []string
+	}{
+		{
+			method: "json",
+			want: []string{
+				"// UserTarget has the same fields as User without its methods, so String does not call itself\ntype UserTarget User\n",
+				"v, err := json.Marshal((*UserTarget)(u))",
+				"v, err := json.Marshal(a)",
+			},
+		},
+		{
+			method:   "jsoniter",
+			receiver: "value",
+			want: []string{
+				"type UserTarget User",
+				"v, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(UserTarget(u))",
+			},
+		},
+		{
+			method: "fmt",
+			want: []string{
+				"type UserTarget User",
+				`return fmt.Sprintf("%+v", *(*UserTarget)(u))`,
+				`return fmt.Sprintf("%+v", *a)`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			o := parseOutput(t, `
+package main
+
+type User struct {
+	Name string
+}
+
+type Admin struct {
+	Name string
+}
+`, tt.method)
+			o.receiver = tt.receiver
+			o.declared = map[string]map[string]bool{"User": {"MarshalJSON": true, "Format": true}}
+
+			res, err := o.gen()
+			assert.NoError(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, string(res), want)
+			}
+			assert.NotContains(t, string(res), "AdminTarget")
+		})
+	}
+}
+
+func TestGenHazardRuns(t *testing.T) {
+	// MarshalJSON calls String through another method, which can not be told from its body
+	src := `
+package main
+
+import "strconv"
+
+type User struct {
+	Name string
+}
+
+func (u *User) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(u.label())), nil
+}
+
+func (u *User) label() string {
+	return "user " + u.String()
+}
+`
+	dir := writeFiles(t, map[string]string{"types.go": src})
+	declared, err := parseDeclared(filepath.Join(dir, "types.go"), "", "main")
+	assert.NoError(t, err)
+	o := parseOutput(t, src, "json")
+	o.skipDeclared(declared)
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	out := run(t, src, res, `
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println((&User{Name: "a"}).String())
+}
+`)
+	assert.Equal(t, `{"Name":"a"}`+"\n", out)
+}
+
+func TestCheckFallback(t *testing.T) {
+	src := `
+package main
+
+type Outer struct {
+	Inner *Inner
+}
+
+type Inner struct {
+	Outers map[string]Outers
+}
+
+type Outers []*Outer
+
+type Leaf struct {
+	Name string
+}
+`
+	tests := []struct {
+		method   string
+		fallback string
+		names    []string
+		wantErr  string
+	}{
+		{method: "json", fallback: "fmt", names: []string{"Outer"}, wantErr: "fallback fmt is not supported for Outer, which refers to itself"},
+		{method: "jsoniter", fallback: "fmt", names: []string{"Inner"}, wantErr: "fallback fmt is not supported for Inner, which refers to itself"},
+		{method: "json", fallback: "fmt", names: []string{"Leaf"}},
+		{method: "json", fallback: "error", names: []string{"Outer"}},
+		{method: "template", fallback: "fmt", names: []string{"Outer"}},
+	}
+
+	for _, tt := range tests {
+		o := parseOutput(t, src, tt.method)
+		o.fallback = tt.fallback
+		o.structNames = tt.names
+
+		err := o.checkFallback()
+		if tt.wantErr == "" {
+			assert.NoError(t, err)
+		} else if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	}
+}
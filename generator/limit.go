@@ -0,0 +1,45 @@
+package generator
+
+import "fmt"
+
+// checkLimits returns an error if the limits of -max-len, -max-elems, -max-string and -max-depth flags can not be applied by the method.
+// The methods marshaling the whole value can only cut their result by -max-len.
+func (o *output) checkLimits() error {
+	if o.maxLen < 0 || o.maxElems < 0 || o.maxString < 0 || o.maxDepth < 0 {
+		return fmt.Errorf("max-len, max-elems, max-string and max-depth must not be negative")
+	}
+	caps := o.caps()
+	if o.maxDepth > 0 && !caps.Nested {
+		if !caps.Fields {
+			return fmt.Errorf("max-depth is not supported by method %s, which marshals the whole value, use method codegen instead", o.method)
+		}
+		return fmt.Errorf("max-depth is not supported by method %s", o.method)
+	}
+	if o.maxElems > 0 && !caps.Nested {
+		return fmt.Errorf("max-elems is not supported by method %s", o.method)
+	}
+	if o.maxString > 0 && !caps.MaxString {
+		return fmt.Errorf("max-string is not supported by method %s", o.method)
+	}
+	if o.maxLen > 0 && !caps.String {
+		return fmt.Errorf("max-len needs String method, which is not generated by method %s", o.method)
+	}
+	return nil
+}
+
+// truncate returns the string expression expr cut to -max-string bytes.
+func (o *output) truncate(expr string) string {
+	if o.maxString == 0 {
+		return expr
+	}
+	return fmt.Sprintf("strgen.Truncate(%s, %d)", expr, o.maxString)
+}
+
+// returnString writes the return statement of a String method whose result expr is cut to -max-len bytes.
+func (o *output) returnString(expr string) {
+	if o.maxLen == 0 {
+		o.addln("return " + expr)
+		return
+	}
+	o.addln(fmt.Sprintf("return strgen.Truncate(%s, %d)", expr, o.maxLen))
+}
@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		method    string
+		maxLen    int
+		maxElems  int
+		maxString int
+		maxDepth  int
+		wantErr   string
+	}{
+		{method: "codegen", maxLen: 10, maxElems: 2, maxString: 3},
+		{method: "append", maxElems: 2},
+		{method: "logfmt", maxString: 3},
+		{method: "json", maxLen: 10},
+		{method: "pretty", maxDepth: 2},
+		{method: "codegen", maxElems: -1, wantErr: "max-len, max-elems, max-string and max-depth must not be negative"},
+		{method: "json", maxDepth: 2, wantErr: "max-depth is not supported by method json, which marshals the whole value, use method codegen instead"},
+		{method: "logfmt", maxDepth: 2, wantErr: "max-depth is not supported by method logfmt"},
+		{method: "logfmt", maxElems: 2, wantErr: "max-elems is not supported by method logfmt"},
+		{method: "fmt", maxString: 3, wantErr: "max-string is not supported by method fmt"},
+		{method: "zap", maxLen: 10, wantErr: "max-len needs String method, which is not generated by method zap"},
+	}
+
+	for _, tt := range tests {
+		o := &output{method: tt.method, maxLen: tt.maxLen, maxElems: tt.maxElems, maxString: tt.maxString, maxDepth: tt.maxDepth}
+		err := o.checkLimits()
+		if tt.wantErr == "" {
+			assert.NoError(t, err, tt.method)
+		} else {
+			assert.EqualError(t, err, tt.wantErr, tt.method)
+		}
+	}
+}
+
+func TestGenLimits(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+}
+`
+	o := parseOutput(t, src, "codegen")
+	o.maxLen, o.maxElems, o.maxString = 100, 2, 8
+	res, err := o.gen()
+	assert.NoError(t, err)
+	for _, want := range []string{
+		"strgen.WriteString(sb, strgen.Truncate(m.Name, 8))",
+		"if i0 == 2 {\n\t\t\t\tstrgen.WriteMore(sb, len(m.Tags)-2)\n\t\t\t\tbreak\n\t\t\t}",
+		"strgen.WriteString(sb, strgen.Truncate(v0, 8))",
+		"for i0, k0 := range strgen.StringKeys(m.Attrs) {",
+		"if i0 == 2 {\n\t\t\t\tstrgen.WriteMore(sb, len(m.Attrs)-2)\n\t\t\t\tsb.WriteString(`:null`)\n\t\t\t\tbreak\n\t\t\t}",
+		"return strgen.Truncate(sb.String(), 100)",
+	} {
+		assert.Contains(t, string(res), want)
+	}
+
+	o = parseOutput(t, src, "append")
+	o.maxElems = 2
+	res, err = o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), "dst = strgen.AppendMore(dst, len(m.Tags)-2)")
+	assert.Contains(t, string(res), "return string(m.AppendString(nil))")
+
+	o = parseOutput(t, src, "json")
+	o.maxLen = 100
+	res, err = o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), "return strgen.Truncate(func() string {")
+	assert.Contains(t, string(res), "}(), 100)")
+
+	o = parseOutput(t, src, "retype")
+	o.maxLen = 100
+	res, err = o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), `return strgen.Truncate(fmt.Sprintf("%+v", (*MyStructTarget)(m)), 100)`)
+}
+
+func TestGenMaxLenNested(t *testing.T) {
+	src := `
+package main
+
+type Inner struct {
+	Name string
+}
+
+type Outer struct {
+	Inner Inner
+	Items []*Inner
+}
+`
+	mainSrc := `
+package main
+
+import "fmt"
+
+func main() {
+	in := Inner{Name: "abcdefghijklmnopqrstuvwxyz"}
+	o := &Outer{Inner: in, Items: []*Inner{&in, &in}}
+	fmt.Println(o.String())
+	fmt.Println(in.String())
+}
+`
+	// nested types are written whole, only the outer String is cut, so that it is still indented
+	for _, method := range []string{"pretty", "append"} {
+		o := parseOutput(t, src, method)
+		o.maxLen = 30
+		o.indent = "  "
+		res, err := o.gen()
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n  \"Inner\": {\n    \"Name\": \"ab...\n{\n  \"Name\": \"abcdefghijklmnopq...\n", run(t, src, res, mainSrc), method)
+	}
+}
+
+func TestGenMaxDepth(t *testing.T) {
+	src := `
+package main
+
+type Node struct {
+	Next     *Node
+	Children Nodes
+}
+
+type Nodes []*Node
+`
+	o := parseOutput(t, src, "codegen")
+	o.maxDepth = 2
+	o.structNames = o.structNames[:1]
+	err := genFile(o, "codegen")
+	assert.NoError(t, err)
+	expected := `package main
+
+import (
+"strconv"
+"strings"
+
+"github.com/chasemao/stringergen/strgen"
+)
+
+// String Used in fmt to generate string
+func (n *Node) String() string {
+if n == nil {
+return "<nil>"
+}
+sb := &strings.Builder{}
+n.writeString(sb, 0)
+return sb.String()
+}
+
+// writeString writes the string to sb, depth is the number of structs nesting it
+func (n *Node) writeString(sb *strings.Builder, depth int) {
+if depth >= 2 {
+sb.WriteString(` + "`" + `"{...}"` + "`" + `)
+return
+}
+sb.WriteString(` + "`" + `{"Next":` + "`" + `)
+if n.Next == nil {
+sb.WriteString(` + "`" + `null` + "`" + `)
+} else {
+n.Next.writeString(sb, depth+1)
+}
+sb.WriteString(` + "`" + `,"Children":` + "`" + `)
+n.Children.writeString(sb, depth)
+sb.WriteString(` + "`" + `}` + "`" + `)
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+
+	o = parseOutput(t, src, "append")
+	o.maxDepth = 2
+	o.cycle = true
+	res, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), "return n.appendString(dst, &strgen.Visited{}, 0)")
+	assert.Contains(t, string(res), "func (n *Node) appendString(dst []byte, visited *strgen.Visited, depth int) []byte {\n\tif depth >= 2 {")
+	assert.Contains(t, string(res), "func (n *Nodes) appendString(dst []byte, visited *strgen.Visited, depth int) []byte {\n\tif !visited.Enter(n) {")
+	assert.Contains(t, string(res), "dst = v0.appendString(dst, visited, depth+1)")
+}
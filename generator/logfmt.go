@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// genLogfmt writes a String method which returns key=value pairs.
+func (o *output) genLogfmt(name string) {
+	n := strings.ToLower(name[0:1])
+	o.addln("// String Used in fmt to generate string")
+	o.addln(fmt.Sprintf("func (%s) String() string {", o.recv(name)))
+	o.nilGuard(n, `"<nil>"`)
+	o.addln("sb := &strings.Builder{}")
+	l := &logfmtgen{body: body{o: o}, first: true, expanding: map[string]bool{name: true}}
+	l.fields("", n, name)
+	l.flush()
+	o.returnString("sb.String()")
+	o.addln("}")
+}
+
+// logfmtgen writes the body of a String method which returns key=value pairs,
+// structs in the same file are flattened into parent.child keys.
+type logfmtgen struct {
+	body
+	// first is true when nothing has been written, pairs after it are separated by space
+	first bool
+	// maybe is true when omitempty fields may have been written while first is true, sb tells whether they are
+	maybe bool
+	// expanding holds the structs being flattened, a struct is not flattened into itself
+	expanding map[string]bool
+}
+
+// fields writes a pair for every field of struct name, keys are prefixed by prefix.
+func (l *logfmtgen) fields(prefix string, expr string, name string) {
+	fields := l.o.structFields(l.o.structType(name))
+	if len(fields) == 0 && prefix != "" {
+		l.key(prefix)
+		l.writeLit("{}")
+		return
+	}
+	for _, f := range fields {
+		key := f.Key
+		if prefix != "" {
+			key = prefix + "." + f.Key
+		}
+		cond := ""
+		if f.OmitEmpty {
+			cond = l.o.notEmpty(sel(expr, f.Name), f.Type)
+		}
+		if cond == "" {
+			l.field(key, sel(expr, f.Name), f)
+			continue
+		}
+		first := l.first
+		l.stmt(fmt.Sprintf("if %s {", cond))
+		l.field(key, sel(expr, f.Name), f)
+		l.stmt("}")
+		if first {
+			l.first = true
+			l.maybe = true
+		}
+	}
+}
+
+// field writes the pairs of field f whose value is expr.
+func (l *logfmtgen) field(key string, expr string, f *Field) {
+	if f.Redact == "full" {
+		l.key(key)
+		l.writeLit(redacted)
+		return
+	}
+	if f.Redact != "" {
+		l.key(key)
+		l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", f.redaction(expr)))
+		return
+	}
+	l.pair(key, expr, f.Type)
+}
+
+func (l *logfmtgen) key(key string) {
+	if !l.first {
+		l.writeLit(" ")
+	} else if l.maybe {
+		l.stmt("if sb.Len() > 0 {")
+		l.writeLit(" ")
+		l.stmt("}")
+	}
+	l.first = false
+	l.writeLit(key + "=")
+}
+
+// pair writes the pairs for expr whose type is typ.
+func (l *logfmtgen) pair(key string, expr string, typ ast.Expr) {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		l.pair(key, expr, t.X)
+		return
+	case *ast.Ident:
+		if l.o.isStruct(t.Name) {
+			if l.expanding[t.Name] {
+				l.key(key)
+				l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", l.o.truncate(sel(expr, "String()"))))
+				return
+			}
+			l.expanding[t.Name] = true
+			l.fields(key, expr, t.Name)
+			delete(l.expanding, t.Name)
+			return
+		}
+		switch basicKind(t.Name) {
+		case "string":
+			l.key(key)
+			l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", l.o.truncate(expr)))
+			return
+		case "bool":
+			l.key(key)
+			l.stmt(fmt.Sprintf("sb.WriteString(strconv.FormatBool(%s))", expr))
+			return
+		case "int":
+			l.key(key)
+			l.stmt(fmt.Sprintf("sb.WriteString(strconv.FormatInt(int64(%s), 10))", expr))
+			return
+		case "uint":
+			l.key(key)
+			l.stmt(fmt.Sprintf("sb.WriteString(strconv.FormatUint(uint64(%s), 10))", expr))
+			return
+		case "float":
+			bits := "64"
+			if t.Name == "float32" {
+				bits = "32"
+			}
+			l.key(key)
+			l.stmt(fmt.Sprintf("sb.WriteString(strconv.FormatFloat(float64(%s), 'g', -1, %s))", expr, bits))
+			return
+		case "error":
+			l.nilable(key, expr, func() {
+				l.key(key)
+				l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", l.o.truncate(sel(expr, "Error()"))))
+			})
+			return
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" {
+			switch t.Sel.Name {
+			case "Time":
+				l.key(key)
+				l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", sel(expr, "Format(time.RFC3339Nano)")))
+				return
+			case "Duration":
+				l.key(key)
+				l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", sel(expr, "String()")))
+				return
+			}
+		}
+	case *ast.StarExpr:
+		l.nilable(key, expr, func() {
+			if id, ok := t.X.(*ast.Ident); ok && l.o.isStruct(id.Name) {
+				// fields are selected through the pointer
+				l.pair(key, expr, id)
+			} else if l.o.isStruct(typeName(t.X)) {
+				// a generic struct is not flattened, the pointer has its String whatever the receiver is
+				l.key(key)
+				l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", l.o.truncate(sel(expr, "String()"))))
+			} else if l.o.definedType(t.X) {
+				// String of the type may have a pointer receiver, which the value does not have
+				l.key(key)
+				l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", l.o.truncate(fmt.Sprintf("fmt.Sprint(%s)", expr))))
+			} else {
+				l.pair(key, "*"+expr, t.X)
+			}
+		})
+		return
+	case *ast.FuncType, *ast.ChanType:
+		l.nilable(key, expr, func() {
+			l.key(key)
+			l.writeLit(placeholder(t))
+		})
+		return
+	}
+	l.key(key)
+	l.stmt(fmt.Sprintf("strgen.WriteLogfmt(sb, %s)", l.o.truncate(fmt.Sprintf("fmt.Sprint(%s)", expr))))
+}
+
+// definedType reports whether typ is a type defined in a package, which may have methods,
+// unlike type parameters and predeclared types like int.
+func (o *output) definedType(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return o.definedType(t.X)
+	case *ast.Ident:
+		return !o.params[t.Name] && types.Universe.Lookup(t.Name) == nil
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return true
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		return !ok || pkg.Name != "time" || (t.Sel.Name != "Time" && t.Sel.Name != "Duration")
+	}
+	return false
+}
+
+// nilable writes key=<nil> when expr is nil, otherwise the pairs written by notNil.
+func (l *logfmtgen) nilable(key string, expr string, notNil func()) {
+	first := l.first
+	l.stmt(fmt.Sprintf("if %s == nil {", expr))
+	l.key(key)
+	l.writeLit("<nil>")
+	l.stmt("} else {")
+	l.first = first
+	notNil()
+	l.stmt("}")
+	l.first = false
+}
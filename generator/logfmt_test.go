@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenLogfmt(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+	Field2 *Sub
+	Field3 []int
+}
+
+type Sub struct {
+	Name string
+	Self *Sub
+}
+`, "logfmt")
+	o.structNames = o.structNames[:1]
+
+	err := genFile(o, "logfmt")
+	assert.NoError(t, err)
+
+	expected := "package main\n" +
+		"\n" +
+		"import (\n" +
+		"\"fmt\"\n" +
+		"\"strconv\"\n" +
+		"\"strings\"\n" +
+		"\"time\"\n" +
+		"\n" +
+		"\"github.com/chasemao/stringergen/strgen\"\n" +
+		")\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (m *MyStruct) String() string {\n" +
+		"if m == nil {\n" +
+		"return \"<nil>\"\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"sb.WriteString(`Field1=`)\n" +
+		"strgen.WriteLogfmt(sb, m.Field1)\n" +
+		"if m.Field2 == nil {\n" +
+		"sb.WriteString(` Field2=<nil>`)\n" +
+		"} else {\n" +
+		"sb.WriteString(` Field2.Name=`)\n" +
+		"strgen.WriteLogfmt(sb, m.Field2.Name)\n" +
+		"if m.Field2.Self == nil {\n" +
+		"sb.WriteString(` Field2.Self=<nil>`)\n" +
+		"} else {\n" +
+		"sb.WriteString(` Field2.Self=`)\n" +
+		"strgen.WriteLogfmt(sb, m.Field2.Self.String())\n" +
+		"}\n" +
+		"}\n" +
+		"sb.WriteString(` Field3=`)\n" +
+		"strgen.WriteLogfmt(sb, fmt.Sprint(m.Field3))\n" +
+		"return sb.String()\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenLogfmtCompiles(t *testing.T) {
+	src := `
+package main
+
+import "time"
+
+type MyStruct struct {
+	A **Sub
+	B error
+	C time.Time
+	D *float32
+}
+
+type Sub struct{}
+`
+	o := parseOutput(t, src, "logfmt")
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	typeCheck(t, src, res)
+}
+
+func TestGenLogfmtPointersAndFuncs(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	Fn    func() error
+	Ch    chan int
+	Pair  *Pair[string, int]
+	Users *Users
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Users []string
+`
+	o := parseOutput(t, src, "logfmt")
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	typeCheck(t, src, res)
+
+	o = parseOutput(t, src, "logfmt")
+	o.structNames = o.structNames[:1]
+	err = genFile(o, "logfmt")
+	assert.NoError(t, err)
+	expected := "package main\n" +
+		"\n" +
+		"import (\n" +
+		"\"fmt\"\n" +
+		"\"strconv\"\n" +
+		"\"strings\"\n" +
+		"\"time\"\n" +
+		"\n" +
+		"\"github.com/chasemao/stringergen/strgen\"\n" +
+		")\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (m *MyStruct) String() string {\n" +
+		"if m == nil {\n" +
+		"return \"<nil>\"\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"if m.Fn == nil {\n" +
+		"sb.WriteString(`Fn=<nil>`)\n" +
+		"} else {\n" +
+		"sb.WriteString(`Fn=<func>`)\n" +
+		"}\n" +
+		"if m.Ch == nil {\n" +
+		"sb.WriteString(` Ch=<nil>`)\n" +
+		"} else {\n" +
+		"sb.WriteString(` Ch=<chan>`)\n" +
+		"}\n" +
+		"if m.Pair == nil {\n" +
+		"sb.WriteString(` Pair=<nil>`)\n" +
+		"} else {\n" +
+		"sb.WriteString(` Pair=`)\n" +
+		"strgen.WriteLogfmt(sb, m.Pair.String())\n" +
+		"}\n" +
+		"if m.Users == nil {\n" +
+		"sb.WriteString(` Users=<nil>`)\n" +
+		"} else {\n" +
+		"sb.WriteString(` Users=`)\n" +
+		"strgen.WriteLogfmt(sb, fmt.Sprint(m.Users))\n" +
+		"}\n" +
+		"return sb.String()\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+}
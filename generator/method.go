@@ -0,0 +1,338 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Method generates the methods of structs for one value of -method flag.
+// Methods are registered by Register, so that programs importing this package can add their own.
+type Method interface {
+	// Imports returns the import specs of the generated file like "strings" or
+	// jsoniter "github.com/json-iterator/go", standard library imports can be omitted as they are added by goimports.
+	Imports(c *Context) ([]string, error)
+	// Helpers writes the declarations shared by all structs of the file, it is called once before Struct.
+	Helpers(c *Context) error
+	// Struct writes the methods of struct name.
+	Struct(c *Context, name string) error
+}
+
+// Capabilities are what the methods written by a Method support beyond a String method of every type,
+// a flag needing a capability fails for a method without it, so that the flag is never ignored silently.
+type Capabilities struct {
+	// String is set if the method writes String methods cut to Context.MaxLen, which -max-len and -formatter need.
+	String bool
+	// LogValue is set if the method writes LogValue methods, so that -logvaluer does not add them again.
+	LogValue bool
+	// Indent is set if String returns multi-line output indented by Context.Indent for -indent flag.
+	Indent bool
+	// Fields is set if the fields of Context.Fields are written one by one, so that -fields flag selects them.
+	Fields bool
+	// AllFields is set if the method prints all fields by itself like %+v, so that -fields=all is accepted.
+	AllFields bool
+	// Tags is set if the fields are written by their Key, OmitEmpty and Redact, stringer tags and -redact flag
+	// fail for the others as well as the types nesting a redacted field.
+	Tags bool
+	// Elements is set if the elements of slices, arrays and maps are written by their own generated methods,
+	// so that their redacted fields are redacted too.
+	Elements bool
+	// Nested is set if nested types are written by writeString or appendString, which -cycle, -max-depth and
+	// -max-elems flags need.
+	Nested bool
+	// MaxString is set if strings are cut to -max-string bytes.
+	MaxString bool
+	// ValueReceiver is set if the methods can have value receivers by -receiver=value.
+	ValueReceiver bool
+	// Fallback is set if String marshals by encoding/json and returns what -fallback flag decides when it fails.
+	Fallback bool
+	// Calls are the methods of a type String calls instead of reading its fields, like MarshalJSON by json.Marshal.
+	// They often call String in turn, so a type having one is printed through a type without methods.
+	Calls []string
+}
+
+// Capable is implemented by a Method declaring its Capabilities, a Method without it has none of them.
+type Capable interface {
+	Capabilities() Capabilities
+}
+
+// capabilities returns the Capabilities of m, none if it does not declare them.
+func capabilities(m Method) Capabilities {
+	if c, ok := m.(Capable); ok {
+		return c.Capabilities()
+	}
+	return Capabilities{}
+}
+
+// caps returns the Capabilities of the method of -method flag, or of the directive group being written.
+func (o *output) caps() Capabilities {
+	return capabilities(methods[o.method])
+}
+
+// Context is the file being generated, it is passed to a Method to read the structs and write code.
+type Context struct {
+	o *output
+}
+
+// Field is a field of a struct selected by -fields flag, embedded fields are named after their type.
+// It is what the builtin methods, custom methods and templates write fields by.
+type Field struct {
+	Name string
+	Type ast.Expr
+	// Tag is the unquoted struct tag like json:"name"
+	Tag      string
+	Embedded bool
+	// Key is the name to write for the field, which is Name unless renamed by a tag like stringer:"name=uid"
+	Key string
+	// OmitEmpty is set by stringer:"omitempty", the method should skip the field if it is empty
+	OmitEmpty bool
+	// Redact is the strategy of stringer:"redact" or -redact flag, which is full for ***, lastN, len or sha256,
+	// the method should not write the value if it is not empty
+	Redact string
+}
+
+// TagValue returns the value associated with key in the tag of the field.
+func (f *Field) TagValue(key string) string {
+	return reflect.StructTag(f.Tag).Get(key)
+}
+
+// Package returns the package name of the file.
+func (c *Context) Package() string {
+	return c.o.pkg
+}
+
+// Structs returns the names of the types to generate methods for in declaration order,
+// they are structs, named slices, maps and arrays, and types defined by them.
+func (c *Context) Structs() []string {
+	return c.o.structNames
+}
+
+// IsStruct reports whether name is a struct returned by Structs, the others are named slices, maps and arrays.
+func (c *Context) IsStruct(name string) bool {
+	return c.o.isStruct(name)
+}
+
+// Type returns the type expression of name, types defined by another type returned by Structs are resolved.
+func (c *Context) Type(name string) ast.Expr {
+	return c.o.underlying(name)
+}
+
+// Receiver returns the receiver name used by the methods of struct name.
+func (c *Context) Receiver(name string) string {
+	return strings.ToLower(name[0:1])
+}
+
+// ReceiverType returns the receiver type used by the methods of struct name, like Page[T] for a generic type.
+func (c *Context) ReceiverType(name string) string {
+	return c.o.recvType(name)
+}
+
+// ValueReceiver reports whether the methods should have value receivers by -receiver=value,
+// otherwise they have pointer receivers and String returns <nil> for a nil pointer.
+func (c *Context) ValueReceiver() bool {
+	return c.o.valueReceiver()
+}
+
+// Fields returns the fields of struct name selected by -fields flag, which are exported ones by default,
+// nil if name is not a struct.
+func (c *Context) Fields(name string) []*Field {
+	st := c.o.structType(name)
+	if st == nil {
+		return nil
+	}
+	return c.o.structFields(st)
+}
+
+// Indent returns the value of -indent flag, String should return multi-line output indented by it if it is not empty.
+func (c *Context) Indent() string {
+	return c.o.indent
+}
+
+// MaxLen returns the value of -max-len flag, String should cut its result to it by strgen.Truncate if it is not zero.
+func (c *Context) MaxLen() int {
+	return c.o.maxLen
+}
+
+// Addln writes a line of code, it is formatted by gofmt afterwards.
+func (c *Context) Addln(s string) {
+	c.o.addln(s)
+}
+
+var methods = make(map[string]Method)
+
+// strgenImport is the import spec of the runtime helpers used by generated methods.
+const strgenImport = `"github.com/chasemao/stringergen/strgen"`
+
+// addImport returns imports with spec added if it is not in imports yet.
+func addImport(imports []string, spec string) []string {
+	for _, s := range imports {
+		if s == spec {
+			return imports
+		}
+	}
+	return append(append([]string(nil), imports...), spec)
+}
+
+// Register makes a method available by name for -method flag,
+// it panics if a method is already registered with the name.
+func Register(name string, m Method) {
+	if m == nil {
+		panic("stringergen: Register method is nil")
+	}
+	if _, dup := methods[name]; dup {
+		panic("stringergen: Register called twice for method " + name)
+	}
+	methods[name] = m
+}
+
+// Methods returns the names of the registered methods in sorted order.
+func Methods() []string {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	// templates marshaling the whole value can not select fields or apply tags
+	marshal := Capabilities{String: true, Indent: true, ValueReceiver: true}
+	marshalJSON := marshal
+	marshalJSON.Fallback, marshalJSON.Calls = true, []string{"MarshalJSON", "MarshalText"}
+	printAll := marshal
+	printAll.AllFields, printAll.ValueReceiver, printAll.Calls = true, false, []string{"Format", "Error"}
+	Register("json", &templateMethod{t: jsonTemplate, caps: marshalJSON})
+	Register("jsoniter", &templateMethod{t: jsonIterTemplate, caps: marshalJSON})
+	Register("fmt", &templateMethod{t: fmtTemplate, caps: printAll})
+	// a template of -template flag gets the fields and the flags, it may call any of the methods
+	Register("template", &templateMethod{caps: Capabilities{String: true, Indent: true, Fields: true, Tags: true, ValueReceiver: true,
+		Calls: []string{"MarshalJSON", "MarshalText", "Format", "Error"}}})
+	Register("retype", &builtin{
+		imports:       []string{`"fmt"`},
+		indentImports: []string{strgenImport},
+		gen:           (*output).genRetype,
+		caps:          Capabilities{String: true, Indent: true, AllFields: true, ValueReceiver: true},
+		check:         (*output).checkRetype,
+	})
+	codegen := Capabilities{String: true, Indent: true, Fields: true, Tags: true, Elements: true, Nested: true, MaxString: true, ValueReceiver: true}
+	Register("codegen", &builtin{
+		imports: []string{`"strconv"`, `"strings"`, "", strgenImport},
+		gen:     (*output).genCodegen,
+		caps:    codegen,
+	})
+	Register("pretty", &builtin{
+		imports: []string{`"strconv"`, `"strings"`, "", strgenImport},
+		gen:     (*output).genPretty,
+		caps:    codegen,
+	})
+	Register("append", &builtin{
+		imports: []string{`"strconv"`, "", strgenImport},
+		gen:     (*output).genAppend,
+		caps:    codegen,
+	})
+	Register("logfmt", &builtin{
+		imports:  []string{`"fmt"`, `"strconv"`, `"strings"`, `"time"`, "", strgenImport},
+		gen:      (*output).genLogfmt,
+		supports: (*output).isStruct,
+		caps:     Capabilities{String: true, Fields: true, Tags: true, MaxString: true, ValueReceiver: true},
+	})
+	Register("slog", &builtin{
+		imports:  []string{`"log/slog"`},
+		gen:      (*output).logValue,
+		supports: (*output).isStruct,
+		caps:     Capabilities{LogValue: true, Fields: true, Tags: true, ValueReceiver: true},
+	})
+	Register("zap", &builtin{
+		imports:  []string{`"go.uber.org/zap/zapcore"`},
+		gen:      (*output).genZap,
+		supports: (*output).zapMarshals,
+		caps:     Capabilities{Fields: true, Tags: true, Elements: true, ValueReceiver: true},
+	})
+}
+
+// builtin is a Method of this package whose code is written by output.
+type builtin struct {
+	imports []string
+	// indentImports are added to imports if -indent flag is set
+	indentImports []string
+	gen           func(o *output, name string)
+	// supports reports whether the type is written, the others are skipped, nil means every type is
+	supports func(o *output, name string) bool
+	caps     Capabilities
+	// check returns an error if the types can not be written by the method, nil means they all can
+	check func(o *output) error
+}
+
+func (b *builtin) Capabilities() Capabilities {
+	return b.caps
+}
+
+func (b *builtin) Imports(c *Context) ([]string, error) {
+	if c.o.indent != "" {
+		return append(append([]string(nil), b.imports...), b.indentImports...), nil
+	}
+	return b.imports, nil
+}
+
+func (b *builtin) Helpers(c *Context) error {
+	return nil
+}
+
+func (b *builtin) Struct(c *Context, name string) error {
+	if b.supports != nil && !b.supports(c.o, name) {
+		d.Printf(yellow+"SKIP TYPE: %s is not supported by method %s"+reset, name, c.o.method)
+		return nil
+	}
+	b.gen(c.o, name)
+	return nil
+}
+
+// imports returns the import specs of the methods of the structs written by m.
+func (o *output) imports(m Method) ([]string, error) {
+	imports, err := m.Imports(&Context{o: o})
+	if err != nil {
+		return nil, err
+	}
+	if o.maxLen > 0 || o.maxString > 0 || o.maxElems > 0 || o.redactsByHelper() || o.goString || o.formatter {
+		imports = addImport(imports, strgenImport)
+	}
+	return imports, nil
+}
+
+// header writes the package clause and the imports of the file.
+func (o *output) header(imports []string) {
+	o.addln("package " + o.pkg)
+	o.addln("")
+	if len(imports) > 0 {
+		o.addln("import (")
+		for _, spec := range imports {
+			o.addln(spec)
+		}
+		o.addln(")")
+		o.addln("")
+	}
+}
+
+// genStructs writes the helpers of m and the methods of the structs by m.
+func (o *output) genStructs(m Method) error {
+	c := &Context{o: o}
+	if err := m.Helpers(c); err != nil {
+		return err
+	}
+	for i, name := range o.structNames {
+		if name == "" {
+			continue
+		}
+		if i != 0 {
+			o.addln("")
+		}
+		o.generating(name)
+		if err := m.Struct(c, name); err != nil {
+			return fmt.Errorf("failed generating %s: %v", name, err)
+		}
+	}
+	return nil
+}
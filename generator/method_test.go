@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fieldsMethod writes a Fields method which returns the field names.
+type fieldsMethod struct{}
+
+func (m *fieldsMethod) Imports(c *Context) ([]string, error) {
+	return nil, nil
+}
+
+func (m *fieldsMethod) Helpers(c *Context) error {
+	c.Addln("var _ = fieldNames")
+	c.Addln("")
+	c.Addln("func fieldNames(names ...string) []string { return names }")
+	c.Addln("")
+	return nil
+}
+
+func (m *fieldsMethod) Struct(c *Context, name string) error {
+	var names []string
+	for _, f := range c.Fields(name) {
+		names = append(names, strconv.Quote(f.Name))
+	}
+	c.Addln(fmt.Sprintf("func (%s *%s) Fields() []string {", c.Receiver(name), name))
+	c.Addln(fmt.Sprintf("return fieldNames(%s)", strings.Join(names, ", ")))
+	c.Addln("}")
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-fields", &fieldsMethod{})
+	defer delete(methods, "test-fields")
+
+	assert.Panics(t, func() { Register("test-fields", &fieldsMethod{}) })
+	assert.Panics(t, func() { Register("test-nil", nil) })
+	assert.Contains(t, Methods(), "test-fields")
+
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A int
+	B string
+}
+`, "test-fields")
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	expected := `package main
+
+var _ = fieldNames
+
+func fieldNames(names ...string) []string { return names }
+
+func (m *MyStruct) Fields() []string {
+	return fieldNames("A", "B")
+}
+`
+	assert.Equal(t, expected, string(res))
+}
+
+// limitMethod writes a String method cut to -max-len bytes.
+type limitMethod struct{}
+
+func (m *limitMethod) Imports(c *Context) ([]string, error) {
+	return []string{`"fmt"`}, nil
+}
+
+func (m *limitMethod) Capabilities() Capabilities {
+	return Capabilities{String: true}
+}
+
+func (m *limitMethod) Helpers(c *Context) error {
+	return nil
+}
+
+func (m *limitMethod) Struct(c *Context, name string) error {
+	res := fmt.Sprintf("fmt.Sprint(*%s)", c.Receiver(name))
+	if c.MaxLen() > 0 {
+		res = fmt.Sprintf("strgen.Truncate(%s, %d)", res, c.MaxLen())
+	}
+	c.Addln(fmt.Sprintf("func (%s *%s) String() string {", c.Receiver(name), name))
+	c.Addln("return " + res)
+	c.Addln("}")
+	return nil
+}
+
+func TestRegisterMaxLen(t *testing.T) {
+	Register("test-limit", &limitMethod{})
+	defer delete(methods, "test-limit")
+
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A int
+}
+`, "test-limit")
+	o.maxLen = 10
+	assert.NoError(t, o.checkLimits())
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	expected := `package main
+
+import (
+	"fmt"
+
+	"github.com/chasemao/stringergen/strgen"
+)
+
+func (m *MyStruct) String() string {
+	return strgen.Truncate(fmt.Sprint(*m), 10)
+}
+`
+	assert.Equal(t, expected, string(res))
+}
+
+func TestRegisterCapabilities(t *testing.T) {
+	Register("test-fields", &fieldsMethod{})
+	defer delete(methods, "test-fields")
+	Register("test-limit", &limitMethod{})
+	defer delete(methods, "test-limit")
+
+	src := `
+package main
+
+type MyStruct struct {
+	A int ` + "`stringer:\"name=a\"`" + `
+}
+`
+	// a method declaring no capabilities gets none of the flags needing them
+	for _, tt := range []struct {
+		method  string
+		set     func(o *output)
+		wantErr string
+	}{
+		{method: "test-fields", set: func(o *output) { o.formatter = true }, wantErr: "formatter needs String method, which is not generated by method test-fields"},
+		{method: "test-fields", set: func(o *output) { o.indent = "  " }, wantErr: "indent is not supported by method test-fields"},
+		{method: "test-fields", set: func(o *output) { o.receiver = "value" }, wantErr: "receiver value is not supported by method test-fields"},
+		{method: "test-limit", set: func(o *output) { o.formatter = true }, wantErr: `stringer tag "name=a" of MyStruct.A is not supported by method test-limit, use method codegen instead`},
+		{method: "test-limit", set: func(o *output) { o.cycle = true }, wantErr: "cycle is not supported by method test-limit"},
+	} {
+		o := parseOutput(t, src, tt.method)
+		tt.set(o)
+		_, err := o.gen()
+		assert.EqualError(t, err, tt.wantErr, tt.method)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	assert.Equal(t, []string{"append", "codegen", "fmt", "json", "jsoniter", "logfmt", "pretty", "retype", "slog", "template", "zap"}, Methods())
+}
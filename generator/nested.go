@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// threaded reports whether String methods of codegen and pretty methods, or AppendString of append method if dst
+// is set, write nested types by writeString or appendString, which pass on the pointers of -cycle flag
+// and the depth of -max-depth flag. With -max-len flag, nested types are written by writeString too, so that only
+// the outer String is cut instead of each nested one, AppendString is never cut.
+func (o *output) threaded(dst bool) bool {
+	return o.cycle || o.maxDepth > 0 || o.maxLen > 0 && !dst
+}
+
+// stateParams returns the parameters of writeString and appendString after sb or dst.
+func (o *output) stateParams() string {
+	var params []string
+	if o.cycle {
+		params = append(params, "visited *strgen.Visited")
+	}
+	if o.maxDepth > 0 {
+		params = append(params, "depth int")
+	}
+	return strings.Join(params, ", ")
+}
+
+// stateArgs returns the arguments of writeString and appendString after sb or dst, visited and depth are
+// the expressions of the pointers being written and the depth, the ones not enabled by flags are left out.
+func (o *output) stateArgs(visited string, depth string) string {
+	var args []string
+	if o.cycle {
+		args = append(args, visited)
+	}
+	if o.maxDepth > 0 {
+		args = append(args, depth)
+	}
+	return strings.Join(args, ", ")
+}
+
+// nestedMethod writes writeString, or appendString if dst is set, which writes the JSON of name like String
+// or AppendString does for the types nesting it. With -cycle flag, it tracks the pointers being written in visited,
+// a pointer met again is written as "<cycle *T>". With -max-depth flag, a struct nested deeper is written as "{...}".
+// It has a pointer receiver whatever -receiver flag is, so that the receiver is tracked.
+func (o *output) nestedMethod(name string, dst bool) {
+	n := strings.ToLower(name[0:1])
+	doc := "// writeString writes the string to sb"
+	sig := fmt.Sprintf("func (%s *%s) writeString(sb *strings.Builder, %s) {", n, o.recvType(name), o.stateParams())
+	if dst {
+		doc = "// appendString appends the string to dst"
+		sig = fmt.Sprintf("func (%s *%s) appendString(dst []byte, %s) []byte {", n, o.recvType(name), o.stateParams())
+	}
+	if o.cycle && dst {
+		doc += ", pointers in visited are being appended, meeting one of them again is a cycle"
+	} else if o.cycle {
+		doc += ", pointers in visited are being written, meeting one of them again is a cycle"
+	}
+	if o.maxDepth > 0 {
+		doc += ", depth is the number of structs nesting it"
+	}
+	depth := o.maxDepth > 0 && o.isStruct(name)
+	o.addln(doc)
+	o.addln(sig)
+	if depth {
+		o.addln(fmt.Sprintf("if depth >= %d {", o.maxDepth))
+		o.writeMarker(`"{...}"`, dst)
+		o.addln("}")
+	}
+	if o.cycle {
+		// a value receiver of String is a copy, its address is never met again,
+		// so a cycle back to it is found at the next copy one level deeper
+		o.addln(fmt.Sprintf("if !visited.Enter(%s) {", n))
+		o.writeMarker(strconv.Quote(fmt.Sprintf("<cycle *%s>", name)), dst)
+		o.addln("}")
+		o.addln(fmt.Sprintf("defer visited.Leave(%s)", n))
+	}
+	c := &codegen{body: body{o: o, dst: dst}}
+	c.object(n, name)
+	if dst {
+		o.addln("return dst")
+	}
+	o.addln("}")
+}
+
+// writeMarker writes the statements returning from writeString or appendString after writing the JSON s.
+func (o *output) writeMarker(s string, dst bool) {
+	if dst {
+		o.addln(fmt.Sprintf("return append(dst, %s...)", goLiteral(s)))
+		return
+	}
+	o.addln(fmt.Sprintf("sb.WriteString(%s)", goLiteral(s)))
+	o.addln("return")
+}
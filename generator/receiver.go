@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// valueReceiver reports whether the methods have value receivers by -receiver=value,
+// so that values and not only pointers implement fmt.Stringer.
+func (o *output) valueReceiver() bool {
+	return o.receiver == "value"
+}
+
+// recv returns the receiver of the methods of name like p *Page[T], or p Page[T] for value receivers.
+func (o *output) recv(name string) string {
+	n := strings.ToLower(name[0:1])
+	if o.valueReceiver() {
+		return n + " " + o.recvType(name)
+	}
+	return n + " *" + o.recvType(name)
+}
+
+// deref returns the expression of the value the receiver n refers to.
+func (o *output) deref(n string) string {
+	if o.valueReceiver() {
+		return n
+	}
+	return "*" + n
+}
+
+// nilGuard writes a return of ret when the pointer receiver n is nil, nothing is written for value receivers.
+func (o *output) nilGuard(n string, ret string) {
+	if o.valueReceiver() {
+		return
+	}
+	o.addln(fmt.Sprintf("if %s == nil {", n))
+	o.addln("return " + ret)
+	o.addln("}")
+}
+
+// checkReceiver returns an error if the methods can not be generated with the receiver of -receiver flag.
+// fmt prints a value by its String method if the value has one, which is the method being generated for value receivers,
+// so the methods printing by %+v or falling back to it do not support them.
+func (o *output) checkReceiver() error {
+	switch o.receiver {
+	case "", "pointer":
+		return nil
+	case "value":
+	default:
+		return fmt.Errorf("unknown receiver: %s", o.receiver)
+	}
+	caps := o.caps()
+	if !caps.ValueReceiver && caps.AllFields {
+		return fmt.Errorf("receiver value is not supported by method %s, whose %%+v would call String itself, use method retype instead", o.method)
+	}
+	if !caps.ValueReceiver {
+		return fmt.Errorf("receiver value is not supported by method %s", o.method)
+	}
+	if caps.Fallback && o.fallback == "fmt" {
+		return fmt.Errorf("receiver value is not supported by fallback fmt, whose %%+v would call String itself")
+	}
+	for _, name := range o.structNames {
+		// a value receiver copies the value, which go vet reports for locks
+		if name != "" && o.holdsLock(o.underlying(name), map[string]bool{name: true}) {
+			return fmt.Errorf("receiver value is not supported for %s, which holds a lock that would be copied", name)
+		}
+	}
+	return nil
+}
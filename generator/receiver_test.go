@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecv(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type Page[T any] struct{}
+`, "")
+
+	assert.Equal(t, "p *Page[T]", o.recv("Page"))
+	assert.Equal(t, "*p", o.deref("p"))
+
+	o.receiver = "value"
+	assert.Equal(t, "p Page[T]", o.recv("Page"))
+	assert.Equal(t, "p", o.deref("p"))
+	o.nilGuard("p", `"<nil>"`)
+	assert.Equal(t, "", o.buf.String())
+}
+
+func TestCheckReceiver(t *testing.T) {
+	tests := []struct {
+		method   string
+		receiver string
+		fallback string
+		wantErr  string
+	}{
+		{method: "fmt", receiver: "pointer"},
+		{method: "json", receiver: "value"},
+		{method: "codegen", receiver: "value"},
+		{method: "json", receiver: "ref", wantErr: "unknown receiver: ref"},
+		{method: "fmt", receiver: "value", wantErr: "receiver value is not supported by method fmt"},
+		{method: "jsoniter", receiver: "value", fallback: "fmt", wantErr: "receiver value is not supported by fallback fmt"},
+		{method: "retype", receiver: "value", wantErr: "receiver value is not supported by method retype for Admin"},
+	}
+
+	for _, tt := range tests {
+		o := parseOutput(t, `
+package main
+
+type User struct{}
+
+type Admin struct {
+	User
+}
+`, tt.method)
+		o.receiver = tt.receiver
+		o.fallback = tt.fallback
+
+		err := o.check()
+		if tt.wantErr == "" {
+			assert.NoError(t, err, tt.method)
+		} else if assert.Error(t, err, tt.method) {
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	}
+}
+
+func TestCheckReceiverLock(t *testing.T) {
+	src := `
+package main
+
+import "sync"
+
+type Direct struct {
+	mu sync.Mutex
+}
+
+type Embedded struct {
+	Inner
+}
+
+type Inner struct {
+	sync.RWMutex
+}
+
+type Alias struct {
+	M Mutex
+}
+
+type Mutex sync.Mutex
+
+type Pointer struct {
+	mu *sync.Mutex
+	In *Inner
+}
+`
+	for _, name := range []string{"Direct", "Embedded", "Alias"} {
+		o := parseOutput(t, src, "codegen")
+		o.receiver = "value"
+		o.structNames = []string{name}
+		assert.EqualError(t, o.check(), "receiver value is not supported for "+name+", which holds a lock that would be copied", name)
+	}
+	// a pointer to a lock is copied by a value receiver
+	o := parseOutput(t, src, "codegen")
+	o.receiver = "value"
+	o.structNames = []string{"Pointer"}
+	assert.NoError(t, o.check())
+}
+
+func TestGenValueReceiver(t *testing.T) {
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{
+			method: "json",
+			want:   []string{"func (u User) String() string {\n\tv, err := json.Marshal(u)"},
+		},
+		{
+			method: "retype",
+			want:   []string{`return fmt.Sprintf("%+v", UsersTarget(u))`},
+		},
+		{
+			method: "codegen",
+			want: []string{
+				"func (u User) String() string {\n\tsb := &strings.Builder{}",
+				"for i0, v0 := range u {",
+			},
+		},
+		{
+			method: "append",
+			want:   []string{"func (u User) AppendString(dst []byte) []byte {\n\tdst = append(dst"},
+		},
+		{
+			method: "logfmt",
+			want:   []string{"func (u User) String() string {\n\tsb := &strings.Builder{}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			o := parseOutput(t, `
+package main
+
+type User struct {
+	Name  string
+	Boss  *User
+	Inner Inner
+}
+
+type Inner struct{}
+
+type Users []*User
+`, tt.method)
+			o.receiver = "value"
+			o.formatter = true
+			o.logValuer = true
+
+			res, err := o.gen()
+			assert.NoError(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, string(res), want)
+			}
+			assert.NotContains(t, string(res), "*User) ")
+			assert.NotContains(t, string(res), "== nil {\n\t\treturn")
+			assert.Contains(t, string(res), "func (u User) Format(st fmt.State, verb rune) {")
+			assert.Contains(t, string(res), `fmt.Fprintf(st, "%%!%c(main.User=%s)", verb, u.String())`)
+			assert.Contains(t, string(res), "func (u User) LogValue() slog.Value {\n\treturn slog.GroupValue(")
+			assert.Contains(t, string(res), "sb.WriteString(`main.User{Name: `)")
+			assert.Contains(t, string(res), "sb.WriteString(`&`)\n\t\tsb.WriteString(u.Boss.GoString())")
+			assert.Contains(t, string(res), "sb.WriteString(u.Inner.GoString())")
+		})
+	}
+}
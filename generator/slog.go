@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// logValue writes a LogValue method which returns a group with one attribute per field.
+func (o *output) logValue(name string) {
+	n := strings.ToLower(name[0:1])
+	o.addln("// LogValue Used in slog to generate structured value")
+	o.addln(fmt.Sprintf("func (%s) LogValue() slog.Value {", o.recv(name)))
+	o.nilGuard(n, "slog.AnyValue(nil)")
+	fields := o.structFields(o.structType(name))
+	conds := make([]string, len(fields))
+	omit := false
+	for i, f := range fields {
+		if f.OmitEmpty {
+			conds[i] = o.notEmpty(n+"."+f.Name, f.Type)
+			omit = omit || conds[i] != ""
+		}
+	}
+	if !omit {
+		o.addln("return slog.GroupValue(")
+		for _, f := range fields {
+			o.addln(o.fieldAttr(n, f) + ",")
+		}
+		o.addln(")")
+		o.addln("}")
+		return
+	}
+	// omitempty fields are appended at run time
+	o.addln(fmt.Sprintf("attrs := make([]slog.Attr, 0, %d)", len(fields)))
+	for i, f := range fields {
+		if conds[i] == "" {
+			o.addln(fmt.Sprintf("attrs = append(attrs, %s)", o.fieldAttr(n, f)))
+			continue
+		}
+		o.addln(fmt.Sprintf("if %s {", conds[i]))
+		o.addln(fmt.Sprintf("attrs = append(attrs, %s)", o.fieldAttr(n, f)))
+		o.addln("}")
+	}
+	o.addln("return slog.GroupValue(attrs...)")
+	o.addln("}")
+}
+
+// fieldAttr returns the slog.Attr expression for field f of receiver n.
+func (o *output) fieldAttr(n string, f *Field) string {
+	if f.Redact != "" {
+		return fmt.Sprintf("slog.String(%q, %s)", f.Key, f.redaction(n+"."+f.Name))
+	}
+	return o.slogAttr(f.Key, n+"."+f.Name, f.Type)
+}
+
+// slogAttr returns the slog.Attr expression for expr whose type is typ.
+func (o *output) slogAttr(key string, expr string, typ ast.Expr) string {
+	key = fmt.Sprintf("%q", key)
+	switch t := typ.(type) {
+	case *ast.Ident:
+		if o.isStruct(t.Name) {
+			// take the address so that the LogValue method of *T is used
+			return fmt.Sprintf("slog.Any(%s, &%s)", key, expr)
+		}
+		switch basicKind(t.Name) {
+		case "string":
+			return fmt.Sprintf("slog.String(%s, %s)", key, expr)
+		case "bool":
+			return fmt.Sprintf("slog.Bool(%s, %s)", key, expr)
+		case "int":
+			return fmt.Sprintf("slog.Int64(%s, int64(%s))", key, expr)
+		case "uint":
+			return fmt.Sprintf("slog.Uint64(%s, uint64(%s))", key, expr)
+		case "float":
+			return fmt.Sprintf("slog.Float64(%s, float64(%s))", key, expr)
+		}
+	case *ast.StarExpr:
+		if id, ok := t.X.(*ast.Ident); ok && o.isStruct(id.Name) && o.valueReceiver() {
+			// slog calls LogValue of a nil pointer, which panics for a value receiver
+			return fmt.Sprintf("slog.Attr{Key: %s, Value: func() slog.Value {\nif %s == nil {\nreturn slog.AnyValue(nil)\n}\nreturn %s.LogValue()\n}()}", key, expr, expr)
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" {
+			switch t.Sel.Name {
+			case "Time":
+				return fmt.Sprintf("slog.Time(%s, %s)", key, expr)
+			case "Duration":
+				return fmt.Sprintf("slog.Duration(%s, %s)", key, expr)
+			}
+		}
+	}
+	return fmt.Sprintf("slog.Any(%s, %s)", key, expr)
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenSlog(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+	Field2 int
+	Field3 Sub
+	Field4 *Sub
+	Field5 time.Duration
+	Field6 []uint8
+}
+
+type Sub struct{}
+`, "slog")
+	o.structNames = o.structNames[:1]
+
+	err := genFile(o, "slog")
+	assert.NoError(t, err)
+
+	expected := `package main
+
+import (
+"log/slog"
+)
+
+// LogValue Used in slog to generate structured value
+func (m *MyStruct) LogValue() slog.Value {
+if m == nil {
+return slog.AnyValue(nil)
+}
+return slog.GroupValue(
+slog.String("Field1", m.Field1),
+slog.Int64("Field2", int64(m.Field2)),
+slog.Any("Field3", &m.Field3),
+slog.Any("Field4", m.Field4),
+slog.Duration("Field5", m.Field5),
+slog.Any("Field6", m.Field6),
+)
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenLogValuer(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+}
+`, "json")
+	o.logValuer = true
+
+	got, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(got), `"log/slog"`)
+	assert.Contains(t, string(got), "func (m *MyStruct) String() string {")
+	assert.Contains(t, string(got), "func (m *MyStruct) LogValue() slog.Value {")
+}
@@ -0,0 +1,691 @@
+// Package generator implements stringergen, which generates string implementations of Go structs.
+// Programs importing it can add their own methods by Register and run the command by Main.
+package generator
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/imports"
+)
+
+var (
+	version = "1.0.0"
+)
+
+// Main runs stringergen with the command line flags. The flags are parsed by a flag set of its own, so that
+// a program registering methods can define flags of the same names.
+func Main() {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	var (
+		// source mode related
+		source      = flags.String("source", "", "(source mode) Input Go source file.")
+		destination = flags.String("destination", "", "(source mode) Output file; defaults to stdout, used in source mode.")
+
+		// recusive mode related
+		recursive = flags.String("recursive", "", "(recursive mode) Input directory, will handle all files recursively.")
+		save      = flags.Bool("save", false, "(recursive mode) Write to file like xx_stringer.go for xx.go, used in recursive mode.")
+		skipdir   = flags.String("skipdir", "", "(recursive mode) Name of directory to skip, not to generate string method within these directories; default to none.")
+
+		// mode free flag
+
+		exclude    = flags.String("exclude", "", "Regular expression patterns for struct names to exclude from generation, separated by commas; Defaults to none.")
+		method     = flags.String("method", "json", "Method for the String method generation. Builtin values: json, jsoniter, fmt, retype, codegen, pretty, append, logfmt, slog, zap, template, -list-methods prints all registered; Defaults to json.")
+		tmpl       = flags.String("template", "", "Path of text/template file rendering String method body, used with -method=template.")
+		logValuer  = flags.Bool("logvaluer", false, "Also generate LogValue method for log/slog alongside String method.")
+		goString   = flags.Bool("gostring", false, "Also generate GoString method which returns a Go composite literal for %#v alongside String method.")
+		maxLen     = flags.Int("max-len", 0, "Maximum length of String output in bytes, longer output is cut and followed by ...; Defaults to no limit.")
+		maxElems   = flags.Int("max-elems", 0, "Maximum number of slice, array and map elements written by codegen, pretty and append methods, the others are written as \"... 42 more\"; Defaults to no limit.")
+		maxString  = flags.Int("max-string", 0, "Maximum length of strings in bytes written by codegen, pretty, append and logfmt methods, longer strings are cut and followed by ...; Defaults to no limit.")
+		redact     = flags.String("redact", "", "Regular expression patterns for field names to redact, separated by commas, their values are written as *** by String methods; Defaults to none.")
+		maxDepth   = flags.Int("max-depth", 0, "Maximum depth of nested structs written by codegen, pretty and append methods, deeper ones are written as \"{...}\"; Defaults to no limit.")
+		indent     = flags.String("indent", "", "Indent of multi-line String output like \"  \", used by json, jsoniter, fmt, retype, codegen, append and template methods; Defaults to single line.")
+		fieldSel   = flags.String("fields", "exported", "Fields written by String method. Supported values: exported, all for unexported fields too, tagged for exported fields and unexported ones with stringer tag; Defaults to exported.")
+		receiver   = flags.String("receiver", "pointer", "Receiver of generated methods. Supported values: pointer, whose methods return <nil> for a nil pointer, value, so that values implement fmt.Stringer too; Defaults to pointer.")
+		fallback   = flags.String("fallback", "error", "What String method returns when json.Marshal fails, used by json and jsoniter methods. Supported values: error for <T: marshal error: ...>, fmt for %+v, empty for empty string; Defaults to error.")
+		enums      = flags.Bool("enum", false, "Also generate String method for named integer types with constants in the file, like golang.org/x/tools/cmd/stringer.")
+		trimPrefix = flags.String("trimprefix", "", "Prefix to trim from constant names in String method of -enum; Defaults to none.")
+		cycle      = flags.Bool("cycle", false, "Track the pointers being written by String methods of codegen, pretty and append methods, a pointer met again is written as \"<cycle *T>\" instead of overflowing the stack.")
+		formatter  = flags.Bool("formatter", false, "Also generate Format method for fmt.Formatter alongside String method, %v is compact, %+v has field names, %#v is Go syntax; it implies -gostring.")
+
+		// common flag
+		listMethods = flags.Bool("list-methods", false, "Print registered methods.")
+		debug       = flags.Bool("v", false, "Output detail information.")
+		showVersion = flags.Bool("version", false, "Printf version.")
+	)
+	flags.Usage = func() { usage(flags) }
+	// flags exits on error
+	_ = flags.Parse(os.Args[1:])
+
+	// handle common flag
+	if *showVersion {
+		printVersion()
+		return
+	}
+	if *listMethods {
+		printMethods()
+		return
+	}
+	d.debug = *debug
+
+	// handle mode free flagv
+	excl, err := compileExcl(*exclude)
+	if err != nil {
+		log.Fatal("Wrong exclude regexp: ", err)
+	}
+
+	skipDirs := parseSkipDir(*skipdir)
+
+	opts := &genOptions{
+		method:     *method,
+		logValuer:  *logValuer,
+		goString:   *goString,
+		formatter:  *formatter,
+		indent:     *indent,
+		fields:     *fieldSel,
+		receiver:   *receiver,
+		fallback:   *fallback,
+		cycle:      *cycle,
+		maxLen:     *maxLen,
+		maxElems:   *maxElems,
+		maxString:  *maxString,
+		maxDepth:   *maxDepth,
+		enum:       *enums,
+		trimPrefix: *trimPrefix,
+	}
+	opts.redact, err = compileExcl(*redact)
+	if err != nil {
+		log.Fatal("Wrong redact regexp: ", err)
+	}
+	opts.template, err = parseTemplate(*method, *tmpl)
+	if err != nil {
+		log.Fatal("Wrong template: ", err)
+	}
+
+	// handle mode
+	if *source != "" {
+		d.Printf(blue + "Source mode start..." + reset)
+		err = genSource(*source, *destination, excl, opts)
+	} else if *recursive != "" {
+		d.Printf(blue + "Recursive mode start..." + reset)
+		err = genRecursive(*recursive, *save, excl, opts, skipDirs)
+	} else {
+		usage(flags)
+		log.Fatal("You must specify source mode or recursive mode")
+	}
+	if err != nil {
+		log.Fatalf("Generate String method failed: %v", err)
+	}
+}
+
+func usage(flags *flag.FlagSet) {
+	_, _ = io.WriteString(os.Stderr, usageText)
+	flags.PrintDefaults()
+}
+
+const usageText = `stringergen has two modes of operation: source and recursive.
+
+Source mode generates string methods for structs from a source file.
+It is enabled by using the -source flag. Other flags that
+may be useful in this mode are -destination.
+If destination not set, it will output to stdout.
+Example:
+	stringergen -source=foo.go -destination=bar.go
+
+Recursive mode generates string methods for all structs in files in subdirectories
+It is enabled by using the -recursive flag. Other flags that
+may be useful in this mode are -save and -skipdir.
+If save flag is set, it will output to xx_stringer.go file when handle structs in xx.go file,
+otherwise it will output to stdout.
+Example:
+	stringergen -recursive=/path/to/directory/ -save -skipdir=/path/to/directory/skip1/,/path/to/directory/skip2/
+
+`
+
+func printVersion() {
+	fmt.Printf("StringerGen version %s", version)
+}
+
+func printMethods() {
+	for _, name := range Methods() {
+		fmt.Println(name)
+	}
+}
+
+var d = &debuger{}
+
+type debuger struct {
+	debug bool
+}
+
+func (d *debuger) Printf(format string, args ...interface{}) {
+	if d.debug {
+		fmt.Printf(format, args...)
+		fmt.Print("\n")
+	}
+}
+
+func compileExcl(excl string) ([]*regexp.Regexp, error) {
+	var exclRes []*regexp.Regexp
+	if excl != "" {
+		patterns := strings.Split(excl, ",")
+		for _, pattern := range patterns {
+			exclRe, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			exclRes = append(exclRes, exclRe)
+		}
+
+		d.Printf("exclude compile success")
+	}
+	return exclRes, nil
+}
+
+func parseSkipDir(dir string) []string {
+	return strings.Split(dir, ",")
+}
+
+const (
+	reset  = "\033[0m"
+	red    = "\033[31m"
+	green  = "\033[32m"
+	yellow = "\033[33m"
+	blue   = "\033[34m"
+	purple = "\033[35m"
+	cyan   = "\033[36m"
+	white  = "\033[37m"
+)
+
+// genOptions holds the flags that decide what code is generated.
+type genOptions struct {
+	method    string
+	logValuer bool
+	goString  bool
+	formatter bool
+	indent    string
+	fields    string
+	receiver  string
+	fallback  string
+	cycle     bool
+	maxLen    int
+	maxElems  int
+	maxString int
+	maxDepth  int
+	// redact matches the names of the fields to redact
+	redact []*regexp.Regexp
+	enum   bool
+	// trimPrefix is trimmed from constant names of enums
+	trimPrefix string
+	// template renders String method bodies of method template
+	template *template.Template
+}
+
+func genSource(source string, destination string, exclRes []*regexp.Regexp, opts *genOptions) error {
+	d.Printf(blue+"Handle %s start..."+reset, source)
+
+	// if not go file, then skip
+	if filepath.Ext(source) != ".go" {
+		d.Printf(yellow+"NOT GO FILE: %s"+reset, source)
+		return nil
+	}
+
+	// read go source file
+	file, err := readGOFile(source)
+	if err != nil {
+		return err
+	}
+	d.Printf("Read Go file %s success", source)
+
+	// parse source file, get information to generate stringerFile file
+	out, err := parseFile(file, exclRes, opts)
+	if err != nil {
+		return err
+	}
+	d.Printf("Parse Go file %s success get structs=%v enums=%d", source, out.structNames, len(out.enums))
+
+	// skip types which already have the methods in other files of the package
+	declared, err := parseDeclared(source, destination, out.pkg)
+	if err != nil {
+		return err
+	}
+	out.skipDeclared(declared)
+
+	// if no struct or enum in file, then skip
+	if len(out.structNames) == 0 && len(out.enums) == 0 {
+		d.Printf(yellow+"NO STRUCT IN FILE: %s"+reset, source)
+		return nil
+	}
+
+	// generate stringer file
+	stringerFile, err := out.gen()
+	if err != nil {
+		return err
+	}
+	d.Printf("Generate String method success for file %s", source)
+
+	// get destination file
+	dstFile, err := getDstFile(destination)
+	if err != nil {
+		return err
+	}
+	d.Printf("Going to generate destination file %s for file %s", dstFile.Name(), source)
+
+	// write to file
+	_, err = dstFile.Write(stringerFile)
+	if err != nil {
+		return err
+	}
+	d.Printf(green+"GENERATE SOURCE FILE SUCCESS: %s"+reset, source)
+
+	return nil
+}
+
+func readGOFile(source string) (*ast.File, error) {
+	fileSet := token.NewFileSet()
+	// comments are parsed for the directives of types
+	file, err := parser.ParseFile(fileSet, source, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing source file %v: %v", source, err)
+	}
+	return file, nil
+}
+
+func getDstFile(destination string) (*os.File, error) {
+	if destination == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(destination)
+}
+
+func parseFile(file *ast.File, exclRes []*regexp.Regexp, opts *genOptions) (*output, error) {
+	out := &output{
+		pkg:         file.Name.Name,
+		method:      opts.method,
+		logValuer:   opts.logValuer,
+		goString:    opts.goString,
+		formatter:   opts.formatter,
+		indent:      opts.indent,
+		fields:      opts.fields,
+		receiver:    opts.receiver,
+		fallback:    opts.fallback,
+		cycle:       opts.cycle,
+		maxLen:      opts.maxLen,
+		maxElems:    opts.maxElems,
+		maxString:   opts.maxString,
+		maxDepth:    opts.maxDepth,
+		redact:      opts.redact,
+		trimPrefix:  opts.trimPrefix,
+		template:    opts.template,
+		specs:       make(map[string]*ast.TypeSpec),
+		typeMethods: make(map[string]string),
+		foreign:     make(map[string]*ast.TypeSpec),
+		locks:       make(map[string]bool),
+	}
+	skipped := make(map[string]bool)
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			switch ts.Type.(type) {
+			case *ast.StructType:
+			case *ast.ArrayType, *ast.MapType, *ast.Ident, *ast.SelectorExpr:
+				// named slices, maps and arrays, and types defined by another type, which is skipped below
+				// unless it is in this file
+				if ts.Assign.IsValid() {
+					continue
+				}
+			default:
+				continue
+			}
+			name := ts.Name.Name
+			skip, method, err := parseDirectives(name, gd.Doc, ts.Doc)
+			if err != nil {
+				return nil, err
+			}
+			if skip {
+				d.Printf(yellow+"SKIP TYPE: %s has directive %sskip"+reset, name, directivePrefix)
+				skipped[name] = true
+				out.foreign[name] = ts
+				continue
+			}
+			if method != "" {
+				out.typeMethods[name] = method
+			}
+			if !matchExcl(name, exclRes) {
+				out.structNames = append(out.structNames, name)
+				out.specs[name] = ts
+			} else {
+				d.Printf("EXCLUDE STRUCT: %s", name)
+				out.foreign[name] = ts
+			}
+		}
+	}
+	// types defined by types from other files or packages are not known, like type Timeout time.Duration
+	var names, unknown []string
+	for _, name := range out.structNames {
+		switch out.underlying(name).(type) {
+		case *ast.Ident, *ast.SelectorExpr:
+			unknown = append(unknown, name)
+		default:
+			names = append(names, name)
+		}
+	}
+	for _, name := range unknown {
+		d.Printf(yellow+"SKIP TYPE: %s is defined by %s, which is not a struct, slice, map or array in the file"+reset, name, types.ExprString(out.underlying(name)))
+		if isLock(out.underlying(name)) {
+			out.locks[name] = true
+		}
+		delete(out.specs, name)
+	}
+	out.structNames = names
+	if opts.enum {
+		for _, e := range parseEnums(file, exclRes) {
+			if !skipped[e.name] {
+				out.enums = append(out.enums, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+func matchExcl(name string, exclRes []*regexp.Regexp) bool {
+	for _, exclRe := range exclRes {
+		if exclRe.Match([]byte(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func genRecursive(root string, save bool, exclRes []*regexp.Regexp, opts *genOptions, skipDirs []string) error {
+	return filepath.WalkDir(root, func(path string, de fs.DirEntry, err error) error {
+		if isInSkipDirs(de, skipDirs) {
+			d.Printf(yellow+"SKIP DIR: %s"+reset, de.Name())
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			return nil
+		}
+		if !save {
+			return genSource(path, "", exclRes, opts)
+		}
+		fileName := filepath.Base(path)
+		ext := filepath.Ext(fileName)
+		dst := path[:len(path)-len(ext)] + "_stringer" + ext
+		return genSource(path, dst, exclRes, opts)
+	})
+}
+
+func isInSkipDirs(d fs.DirEntry, skipDirs []string) bool {
+	if !d.IsDir() {
+		return false
+	}
+	for _, skipDir := range skipDirs {
+		if d.Name() == skipDir {
+			return true
+		}
+	}
+	return false
+}
+
+type output struct {
+	buf strings.Builder
+	pkg string
+	// structNames holds the types to generate, named slices, maps and arrays are included
+	structNames []string
+	method      string
+	// logValuer adds LogValue methods after the String methods
+	logValuer bool
+	// goString adds GoString methods after the String methods
+	goString bool
+	// formatter adds Format methods after the String methods, GoString methods are added for %#v
+	formatter bool
+	// indent makes String methods return multi-line output indented by it
+	indent string
+	// fields is exported, all or tagged, empty means exported
+	fields string
+	// receiver is pointer or value, empty means pointer
+	receiver string
+	// fallback decides what String methods return when marshal fails, empty means error
+	fallback string
+	// cycle makes codegen String methods track the pointers being written
+	cycle bool
+	// maxLen, maxElems, maxString and maxDepth limit the output size, zero means no limit
+	maxLen    int
+	maxElems  int
+	maxString int
+	maxDepth  int
+	// redact matches the names of the fields written as ***
+	redact []*regexp.Regexp
+	// enums get switch based String methods whatever the method is
+	enums      []*enum
+	trimPrefix string
+	// template renders String method bodies of method template
+	template *template.Template
+	// params holds the type parameters of the type being generated
+	params map[string]bool
+	// declared holds the methods declared in the package by type name, types having them are skipped
+	declared map[string]map[string]bool
+	// specs holds the type spec of every struct in structNames, used by the field by field backends
+	specs map[string]*ast.TypeSpec
+	// typeMethods holds the methods of types set by //stringergen:method directive instead of -method flag
+	typeMethods map[string]string
+	// foreign holds the types of the file which are not generated, skipped by a directive, -exclude or the methods
+	// declared in the package, they are written like types of another package and may reach redacted fields
+	foreign map[string]*ast.TypeSpec
+	// locks holds the types defined by locks of another package like type Mutex sync.Mutex, which are not in specs
+	locks map[string]bool
+}
+
+func (o *output) gen() ([]byte, error) {
+	o.buf = strings.Builder{}
+	// the types of a method directive are written group by group, the others are written like types of another package
+	method, names, specs := o.method, o.structNames, o.specs
+	defer func() {
+		o.method, o.structNames, o.specs = method, names, specs
+	}()
+	groups := o.methodGroups()
+	var importSpecs []string
+	for _, g := range groups {
+		o.use(g)
+		err := o.check()
+		var groupImports []string
+		if err == nil {
+			groupImports, err = o.imports(methods[o.method])
+		}
+		if err != nil {
+			if g.method != method {
+				return nil, fmt.Errorf("%s of directive %smethod=%s: %v", strings.Join(g.names, ", "), directivePrefix, g.method, err)
+			}
+			return nil, err
+		}
+		for _, spec := range groupImports {
+			importSpecs = addImport(importSpecs, spec)
+		}
+	}
+	// tags are checked against every type, a redacted field may be reached through a type of another group
+	o.method, o.structNames, o.specs = method, names, specs
+	if err := o.checkTags(); err != nil {
+		return nil, err
+	}
+	o.header(importSpecs)
+	for i, g := range groups {
+		o.use(g)
+		if i != 0 {
+			o.addln("")
+		}
+		if err := o.genStructs(methods[o.method]); err != nil {
+			return nil, err
+		}
+		o.genExtras()
+	}
+	for _, e := range o.enums {
+		o.addln("")
+		o.enumString(e)
+	}
+	res := o.buf.String()
+	return imports.Process("", []byte(res), nil)
+}
+
+// check returns an error if the flags can not be applied by the method.
+func (o *output) check() error {
+	if _, ok := methods[o.method]; !ok {
+		return fmt.Errorf("unknown method: %s", o.method)
+	}
+	switch o.fallback {
+	case "", "error", "fmt", "empty":
+	default:
+		return fmt.Errorf("unknown fallback: %s", o.fallback)
+	}
+	if err := o.checkFallback(); err != nil {
+		return err
+	}
+	if err := o.checkFields(); err != nil {
+		return err
+	}
+	if err := o.checkLimits(); err != nil {
+		return err
+	}
+	if err := o.checkReceiver(); err != nil {
+		return err
+	}
+	caps := o.caps()
+	if o.cycle && !caps.Nested {
+		return fmt.Errorf("cycle is not supported by method %s", o.method)
+	}
+	if o.cycle && (o.formatter || o.goString) {
+		// Format and GoString write nested values without visited pointers, a cycle would overflow the stack
+		return fmt.Errorf("cycle is not supported with formatter or gostring, whose methods do not track the pointers being written")
+	}
+	if o.indent != "" && !caps.Indent {
+		return fmt.Errorf("indent is not supported by method %s", o.method)
+	}
+	if o.formatter && !caps.String {
+		return fmt.Errorf("formatter needs String method, which is not generated by method %s", o.method)
+	}
+	if b, ok := methods[o.method].(*builtin); ok && b.check != nil {
+		return b.check(o)
+	}
+	return nil
+}
+
+// genExtras writes the methods of -logvaluer, -gostring and -formatter flags alongside the ones of the method.
+func (o *output) genExtras() {
+	if o.logValuer && !o.caps().LogValue {
+		// imports.Process adds the log/slog import
+		for _, name := range o.undeclared("LogValue") {
+			o.addln("")
+			o.generating(name)
+			o.logValue(name)
+		}
+	}
+	if o.goString || o.formatter {
+		for _, name := range o.undeclared("GoString") {
+			o.addln("")
+			o.generating(name)
+			o.goStringMethod(name)
+		}
+	}
+	if o.formatter {
+		for _, name := range o.undeclared("Format") {
+			o.addln("")
+			o.generating(name)
+			o.formatMethod(name)
+		}
+	}
+}
+
+// structs returns the names of structs in structNames, the extra methods are generated for structs only.
+func (o *output) structs() []string {
+	var names []string
+	for _, name := range o.structNames {
+		if o.isStruct(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (o *output) addln(s string) {
+	o.buf.WriteString(s)
+	o.buf.WriteByte('\n')
+}
+
+// genRetype writes a String method printing the fields by fmt through a type without methods.
+func (o *output) genRetype(name string) {
+	n := strings.ToLower(name[0:1])
+	o.targetType(name)
+	o.addln("// String Used in fmt to generate string")
+	o.addln(fmt.Sprintf("func (%s) String() string {", o.recv(name)))
+	o.nilGuard(n, `"<nil>"`)
+	target := o.target(name)
+	if o.retypeDeref(name) {
+		target = "*" + target
+	}
+	res := fmt.Sprintf(`fmt.Sprintf("%%+v", %s)`, target)
+	if o.indent != "" {
+		res = fmt.Sprintf("strgen.IndentFmt(%s, %q)", res, o.indent)
+	}
+	o.returnString(res)
+	o.addln("}")
+}
+
+// retypeDeref reports whether String of retype method prints the value of the receiver converted to the target type.
+// String of an embedded field is promoted to *xTarget, so the value whose method set does not have it is printed,
+// named slices, maps and arrays are printed without & too.
+func (o *output) retypeDeref(name string) bool {
+	return !o.valueReceiver() && ((o.isNamed(name) && !o.isStruct(name)) || o.hasEmbedded(name))
+}
+
+// checkRetype returns an error if String of retype method would call itself or copy a lock: String of an embedded
+// field is promoted to xTarget of a value receiver, and printing the value of a type copies it.
+func (o *output) checkRetype() error {
+	for _, name := range o.structNames {
+		if name == "" {
+			continue
+		}
+		if o.valueReceiver() && o.hasEmbedded(name) {
+			return fmt.Errorf("receiver value is not supported by method retype for %s, String of its embedded fields would be promoted to %sTarget", name, name)
+		}
+		if o.retypeDeref(name) && o.holdsLock(o.underlying(name), map[string]bool{name: true}) {
+			return fmt.Errorf("method retype would copy the lock held by %s, whose value is printed as it has embedded fields or is a named array, use method codegen instead", name)
+		}
+	}
+	return nil
+}
+
+// hasEmbedded reports whether struct name has embedded fields.
+func (o *output) hasEmbedded(name string) bool {
+	st := o.structType(name)
+	if st == nil {
+		return false
+	}
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			return true
+		}
+	}
+	return false
+}
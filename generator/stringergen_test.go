@@ -1,15 +1,29 @@
-package main
+package generator
 
 import (
+	"flag"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"io/fs"
+	"os"
 	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+func TestMainFlags(t *testing.T) {
+	// a program registering methods may define flags of the same names, Main parses its own
+	if flag.Lookup("v") == nil {
+		flag.Bool("v", false, "verbose")
+	}
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"stringergen", "-v", "-list-methods"}
+	Main()
+}
+
 func TestCompileExcl(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -342,7 +356,7 @@ var x int
 			}
 
 			// Call the parseFile function
-			got, err := parseFile(file, excl, "")
+			got, err := parseFile(file, excl, &genOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseFile() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -376,7 +390,8 @@ func TestGenJSON(t *testing.T) {
 		structNames: []string{"MyStruct"},
 	}
 
-	o.genJSON()
+	err := genFile(o, "json")
+	assert.NoError(t, err)
 
 	expected := `package main
 
@@ -386,20 +401,80 @@ import (
 
 // String Used in fmt to generate string
 func (m *MyStruct) String() string {
-v, _ := json.Marshal(m)
+if m == nil {
+return "<nil>"
+}
+v, err := json.Marshal(m)
+if err != nil {
+return "<MyStruct: marshal error: " + err.Error() + ">"
+}
 return string(v)
 }
 `
 	assert.Equal(t, expected, o.buf.String())
 }
 
+func TestGenJSONFallback(t *testing.T) {
+	tests := []struct {
+		fallback string
+		want     string
+	}{
+		{
+			fallback: "error",
+			want: `	v, err := json.Marshal(m)
+	if err != nil {
+		return "<MyStruct: marshal error: " + err.Error() + ">"
+	}
+	return string(v)`,
+		},
+		{
+			fallback: "fmt",
+			want: `	v, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf("%+v", *m)
+	}
+	return string(v)`,
+		},
+		{
+			fallback: "empty",
+			want: `	v, _ := json.Marshal(m)
+	return string(v)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fallback, func(t *testing.T) {
+			o := &output{
+				pkg:         "main",
+				structNames: []string{"MyStruct"},
+				method:      "json",
+				fallback:    tt.fallback,
+			}
+
+			res, err := o.gen()
+			assert.NoError(t, err)
+			assert.Contains(t, string(res), tt.want)
+		})
+	}
+
+	o := &output{
+		pkg:         "main",
+		structNames: []string{"MyStruct"},
+		method:      "json",
+		fallback:    "panic",
+	}
+	_, err := o.gen()
+	assert.Error(t, err)
+}
+
 func TestGenJSONIter(t *testing.T) {
 	o := &output{
 		pkg:         "main",
 		structNames: []string{"MyStruct"},
 	}
 
-	o.genJSONIter()
+	err := genFile(o, "jsoniter")
+	assert.NoError(t, err)
 
 	expected := `package main
 
@@ -409,7 +484,13 @@ jsoniter "github.com/json-iterator/go"
 
 // String Used in fmt to generate string
 func (m *MyStruct) String() string {
-v, _ := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(m)
+if m == nil {
+return "<nil>"
+}
+v, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(m)
+if err != nil {
+return "<MyStruct: marshal error: " + err.Error() + ">"
+}
 return string(v)
 }
 `
@@ -422,7 +503,8 @@ func TestGenFmt(t *testing.T) {
 		structNames: []string{"MyStruct"},
 	}
 
-	o.genFmt()
+	err := genFile(o, "fmt")
+	assert.NoError(t, err)
 
 	expected := `package main
 
@@ -432,8 +514,243 @@ import (
 
 // String Used in fmt to generate string
 func (m *MyStruct) String() string {
+if m == nil {
+return "<nil>"
+}
 return fmt.Sprintf("%+v",*m)
 }
 `
 	assert.Equal(t, expected, o.buf.String())
 }
+
+func TestGenRetype(t *testing.T) {
+	o := &output{
+		pkg:         "main",
+		structNames: []string{"MyStruct"},
+	}
+
+	err := genFile(o, "retype")
+	assert.NoError(t, err)
+
+	expected := `package main
+
+import (
+"fmt"
+)
+
+// MyStructTarget has the same fields as MyStruct without its methods, so String does not call itself
+type MyStructTarget MyStruct
+
+// String Used in fmt to generate string
+func (m *MyStruct) String() string {
+if m == nil {
+return "<nil>"
+}
+return fmt.Sprintf("%+v", (*MyStructTarget)(m))
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenRetypeEmbedded(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", `
+package main
+
+type MyStruct struct {
+	Sub
+}
+`, parser.AllErrors)
+	if err != nil {
+		t.Fatalf("parser.ParseFile() error: %v", err)
+	}
+	o, err := parseFile(file, nil, &genOptions{method: "retype"})
+	if err != nil {
+		t.Fatalf("parseFile() error: %v", err)
+	}
+
+	err = genFile(o, "retype")
+	assert.NoError(t, err)
+
+	assert.Contains(t, o.buf.String(), `return fmt.Sprintf("%+v", *(*MyStructTarget)(m))`)
+}
+
+func TestCheckRetypeLock(t *testing.T) {
+	src := `
+package main
+
+import "sync"
+
+type Sub struct {
+	mu sync.Mutex
+}
+
+type Plain struct {
+	Sub
+}
+
+type Shared struct {
+	Name string
+	mu   *sync.Mutex
+}
+
+type Embedded struct {
+	Shared
+}
+
+type Subs []Sub
+
+type Pair [2]Sub
+`
+	for _, tt := range []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "Plain", wantErr: "method retype would copy the lock held by Plain, whose value is printed as it has embedded fields or is a named array, use method codegen instead"},
+		{name: "Pair", wantErr: "method retype would copy the lock held by Pair, whose value is printed as it has embedded fields or is a named array, use method codegen instead"},
+		// Sub is printed through a pointer, a pointer or a slice shares the lock
+		{name: "Sub"},
+		{name: "Embedded"},
+		{name: "Subs"},
+	} {
+		o := parseOutput(t, src, "retype")
+		o.structNames = []string{tt.name}
+		err := o.checkRetype()
+		if tt.wantErr == "" {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.EqualError(t, err, tt.wantErr, tt.name)
+		}
+	}
+}
+
+func TestGenIndent(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{
+			method: "json",
+			want:   `v, err := json.MarshalIndent(m, "", "\t")`,
+		},
+		{
+			method: "jsoniter",
+			want:   `v, err := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalIndent(m, "", "\t")`,
+		},
+		{
+			method: "fmt",
+			want:   `return strgen.IndentFmt(fmt.Sprintf("%+v", *m), "\t")`,
+		},
+		{
+			method: "retype",
+			want:   `return strgen.IndentFmt(fmt.Sprintf("%+v", (*MyStructTarget)(m)), "\t")`,
+		},
+		{
+			method: "codegen",
+			want:   `return strgen.IndentJSON(sb.String(), "\t")`,
+		},
+		{
+			method: "pretty",
+			want:   `return strgen.IndentJSON(sb.String(), "\t")`,
+		},
+		{
+			method: "append",
+			want:   `return strgen.IndentJSON(string(m.AppendString(nil)), "\t")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A int
+	B *MyStruct
+}
+`, tt.method)
+			o.indent = "\t"
+
+			res, err := o.gen()
+			assert.NoError(t, err)
+			assert.Contains(t, string(res), tt.want)
+		})
+	}
+}
+
+func TestGenIndentUnsupported(t *testing.T) {
+	for _, method := range []string{"logfmt", "slog", "zap"} {
+		o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A int
+}
+`, method)
+		o.indent = "  "
+
+		_, err := o.gen()
+		assert.Error(t, err)
+	}
+}
+
+func TestGenPretty(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A int
+}
+`, "pretty")
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), `return strgen.IndentJSON(sb.String(), "  ")`)
+}
+
+func TestParseFileNamedTypes(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type User struct{}
+
+type Users []*User
+
+type Index map[string]*User
+
+type Pair [2]int
+
+type Admin User
+
+type Admins Users
+
+type Timeout time.Duration
+
+type Status int
+
+type Other pkg.Struct
+
+type Func func()
+
+type Alias = []int
+
+type List[T any] []T
+`, "json")
+
+	assert.Equal(t, []string{"User", "Users", "Index", "Pair", "Admin", "Admins", "List"}, o.structNames)
+	assert.True(t, o.isStruct("Admin"))
+	assert.False(t, o.isStruct("Admins"))
+	assert.IsType(t, &ast.ArrayType{}, o.underlying("Admins"))
+}
+
+func TestGenRetypeNamedType(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type Users []string
+`, "retype")
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), `return fmt.Sprintf("%+v", *(*UsersTarget)(u))`)
+}
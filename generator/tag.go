@@ -0,0 +1,383 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// redacted is written in place of the value of a field redacted fully.
+const redacted = "***"
+
+// applyTag sets the options of the stringer tag of f like `stringer:"name=uid,omitempty,redact"`,
+// it returns false if the field is omitted by `stringer:"-"`. A field whose name matches -redact flag is redacted fully
+// unless its tag sets another strategy.
+func (o *output) applyTag(f *Field) bool {
+	f.Key = f.Name
+	if matchExcl(f.Name, o.redact) {
+		f.Redact = "full"
+	}
+	tag, ok := reflect.StructTag(f.Tag).Lookup("stringer")
+	if !ok || tag == "" {
+		return true
+	}
+	if tag == "-" {
+		return false
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		switch opt = strings.TrimSpace(opt); {
+		case opt == "":
+		case strings.HasPrefix(opt, "name="):
+			f.Key = strings.TrimPrefix(opt, "name=")
+		case opt == "omitempty":
+			f.OmitEmpty = true
+		case opt == "redact":
+			f.Redact = "full"
+		case strings.HasPrefix(opt, "redact="):
+			f.Redact = strings.TrimPrefix(opt, "redact=")
+		}
+	}
+	return true
+}
+
+// redaction returns the expression of the string written in place of expr, the value of field f, by its redact strategy.
+func (f *Field) redaction(expr string) string {
+	switch f.Redact {
+	case "len":
+		return fmt.Sprintf("strgen.RedactLen(%s)", expr)
+	case "sha256":
+		return fmt.Sprintf("strgen.RedactHash(%s)", expr)
+	}
+	if n, ok := redactLast(f.Redact); ok {
+		return fmt.Sprintf("strgen.RedactLast(%s, %d)", expr, n)
+	}
+	return strconv.Quote(redacted)
+}
+
+// redactLast returns N of redact strategy lastN, which keeps the last N characters.
+func redactLast(strategy string) (int, bool) {
+	if !strings.HasPrefix(strategy, "last") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(strategy, "last"))
+	return n, err == nil && n > 0
+}
+
+// checkOptions returns an error if the stringer tag of field f of struct name has an unknown option,
+// so that a misspelt redact can not write the value.
+func (o *output) checkOptions(name string, f *Field) error {
+	for _, opt := range strings.Split(reflect.StructTag(f.Tag).Get("stringer"), ",") {
+		switch opt = strings.TrimSpace(opt); {
+		case opt == "", opt == "omitempty", opt == "redact", strings.HasPrefix(opt, "name="), strings.HasPrefix(opt, "redact="):
+		default:
+			return fmt.Errorf("unknown option %s in stringer tag of %s.%s", opt, name, f.Name)
+		}
+	}
+	return nil
+}
+
+// checkRedact returns an error if the redact strategy of field f of struct name is unknown,
+// the strategies other than full need a string field.
+func (o *output) checkRedact(name string, f *Field) error {
+	switch _, last := redactLast(f.Redact); {
+	case f.Redact == "" || f.Redact == "full":
+		return nil
+	case f.Redact != "len" && f.Redact != "sha256" && !last:
+		return fmt.Errorf("unknown redact strategy %s of %s.%s", f.Redact, name, f.Name)
+	}
+	if id, ok := f.Type.(*ast.Ident); !ok || id.Name != "string" {
+		return fmt.Errorf("redact=%s of %s.%s needs a string field", f.Redact, name, f.Name)
+	}
+	return nil
+}
+
+// checkTags returns an error if a struct has a stringer tag with options or a field matching -redact flag,
+// which its method ignores, or an unknown tag option or a redact strategy which can not be applied. The methods
+// without Capabilities.Tags, like the ones marshaling the whole value which are told by json tags or print all fields,
+// can not guarantee that redacted fields are not shown, neither the ones of the types they nest. It is run against every type of the file before they are
+// grouped by method directives, as a redacted field may be reached through a type of another group.
+func (o *output) checkTags() error {
+	for _, name := range o.structNames {
+		st := o.structType(name)
+		if st == nil {
+			continue
+		}
+		m := o.methodOf(name)
+		caps := capabilities(methods[m])
+		check := o.checkMarshaled
+		if caps.Tags {
+			check = o.checkStruct
+		}
+		if err := check(name, st, m); err != nil {
+			return err
+		}
+		if _, ok := methods[m].(*builtin); !ok && caps.Tags && o.reachesRedacted(ast.NewIdent(name), make(map[string]bool)) {
+			// templates and custom methods get Field.Redact, nothing checks that they do not print the value
+			d.Printf(yellow+"REDACT: %s reaches redacted fields, method %s must write them by Field.Redact"+reset, name, m)
+		}
+	}
+	return nil
+}
+
+// checkMarshaled returns the error of checkTags for struct name written by method m, which does not apply tags.
+func (o *output) checkMarshaled(name string, st *ast.StructType, m string) error {
+	for _, f := range st.Fields.List {
+		field := embeddedName(f.Type)
+		if len(f.Names) > 0 {
+			field = f.Names[0].Name
+		}
+		for _, n := range f.Names {
+			if matchExcl(n.Name, o.redact) {
+				return fmt.Errorf("field %s.%s matches -redact, which is not supported by method %s, use method codegen instead", name, n.Name, m)
+			}
+		}
+		if f.Tag != nil {
+			tag, _ := strconv.Unquote(f.Tag.Value)
+			if v := reflect.StructTag(tag).Get("stringer"); v != "" {
+				return fmt.Errorf("stringer tag %q of %s.%s is not supported by method %s, use method codegen instead", v, name, field, m)
+			}
+		}
+		if o.reachesRedacted(f.Type, map[string]bool{name: true}) {
+			return fmt.Errorf("field %s.%s reaches redacted fields, which are not supported by method %s, use method codegen instead", name, field, m)
+		}
+	}
+	return nil
+}
+
+// checkStruct returns the error of checkTags for struct name written field by field by method m, which applies tags.
+func (o *output) checkStruct(name string, st *ast.StructType, m string) error {
+	for _, f := range o.structFields(st) {
+		if err := o.checkOptions(name, f); err != nil {
+			return err
+		}
+		if err := o.checkRedact(name, f); err != nil {
+			return err
+		}
+		if f.Redact != "" {
+			continue
+		}
+		if o.redactedElem(f.Type) {
+			if by := o.elemsWriter(name, m); by != "" {
+				return fmt.Errorf("elements of %s.%s have redacted fields, which are not redacted in a slice, array or map by %s", name, f.Name, by)
+			}
+		}
+		if by := o.marshaledRedacted(f.Type, m, make(map[string]bool)); by != "" {
+			return fmt.Errorf("field %s.%s reaches redacted fields of %s, which method %s writes like a type of another package without redacting them", name, f.Name, by, m)
+		}
+	}
+	return nil
+}
+
+// elemsWriter returns what writes the slices, arrays and maps of struct name by fmt or slog, which print every field
+// of their elements, empty if method m and the methods of the flags write each element by its own generated method.
+func (o *output) elemsWriter(name string, m string) string {
+	caps := capabilities(methods[m])
+	switch {
+	case !caps.Elements:
+		return "method " + m + ", use method codegen instead"
+	case o.logValuer && !caps.LogValue && o.declaredMethod(name, "LogValue") == "":
+		return "-logvaluer"
+	case o.formatter && o.declaredMethod(name, "Format") == "":
+		return "-formatter"
+	}
+	return ""
+}
+
+// reachesRedacted reports whether a value of typ writes a redacted field of a struct in this output or in foreign,
+// which is found through the fields of structs and the elements of pointers, slices, arrays and maps.
+// seen holds the types visited, so that a recursive type is visited once.
+func (o *output) reachesRedacted(typ ast.Expr, seen map[string]bool) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return o.reachesRedacted(t.X, seen)
+	case *ast.StarExpr:
+		return o.reachesRedacted(t.X, seen)
+	case *ast.ArrayType:
+		return o.reachesRedacted(t.Elt, seen)
+	case *ast.MapType:
+		return o.reachesRedacted(t.Key, seen) || o.reachesRedacted(t.Value, seen)
+	case *ast.StructType:
+		for _, f := range o.structFields(t) {
+			if f.Redact != "" || o.reachesRedacted(f.Type, seen) {
+				return true
+			}
+		}
+	case *ast.Ident, *ast.IndexExpr, *ast.IndexListExpr:
+		name := typeName(t)
+		if ts, ok := o.foreign[name]; ok && !seen[name] && !o.params[name] {
+			seen[name] = true
+			return o.reachesRedacted(ts.Type, seen)
+		}
+		if seen[name] || !o.isNamed(name) {
+			return false
+		}
+		seen[name] = true
+		st := o.structType(name)
+		if st == nil {
+			return o.reachesRedacted(o.underlying(name), seen)
+		}
+		for _, f := range o.structFields(st) {
+			if f.Redact != "" || o.reachesRedacted(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// marshaledRedacted returns the type of the file which typ reaches and method m writes like a type of another package,
+// by encoding/json, fmt or slog which print every field, though it reaches a redacted field. It is a type of another
+// method directive or in foreign, or an anonymous struct, empty if there is none.
+func (o *output) marshaledRedacted(typ ast.Expr, m string, seen map[string]bool) string {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return o.marshaledRedacted(t.X, m, seen)
+	case *ast.StarExpr:
+		return o.marshaledRedacted(t.X, m, seen)
+	case *ast.ArrayType:
+		return o.marshaledRedacted(t.Elt, m, seen)
+	case *ast.MapType:
+		if by := o.marshaledRedacted(t.Key, m, seen); by != "" {
+			return by
+		}
+		return o.marshaledRedacted(t.Value, m, seen)
+	case *ast.StructType:
+		if o.reachesRedacted(t, make(map[string]bool)) {
+			return "struct{...}"
+		}
+	case *ast.Ident, *ast.IndexExpr, *ast.IndexListExpr:
+		name := typeName(t)
+		if seen[name] || o.params[name] {
+			return ""
+		}
+		seen[name] = true
+		_, foreign := o.foreign[name]
+		if foreign || o.isNamed(name) && o.methodOf(name) != m {
+			if o.reachesRedacted(t, make(map[string]bool)) {
+				return name
+			}
+			return ""
+		}
+		// the structs of m are checked by themselves
+		if o.isNamed(name) && !o.isStruct(name) {
+			return o.marshaledRedacted(o.underlying(name), m, seen)
+		}
+	}
+	return ""
+}
+
+// redactedElem reports whether typ is a slice, array or map, or a pointer to or a type defined by one,
+// whose elements reach a redacted field.
+func (o *output) redactedElem(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return o.redactedElem(t.X)
+	case *ast.StarExpr:
+		return o.redactedElem(t.X)
+	case *ast.ArrayType, *ast.MapType:
+		return o.reachesRedacted(t, make(map[string]bool))
+	case *ast.Ident:
+		if o.isNamed(t.Name) && !o.isStruct(t.Name) {
+			return o.redactedElem(o.underlying(t.Name))
+		}
+	}
+	return false
+}
+
+// redactsByHelper reports whether a field is redacted by a strgen function, which is not imported by every method.
+func (o *output) redactsByHelper() bool {
+	for _, name := range o.structNames {
+		st := o.structType(name)
+		if st == nil {
+			continue
+		}
+		for _, f := range o.structFields(st) {
+			if f.Redact != "" && f.Redact != "full" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notEmpty returns the condition that expr whose type is typ is not empty like encoding/json defines for omitempty,
+// which is false, 0, a nil pointer or interface and an empty slice, map or string. It returns empty for structs
+// and types that are not known here, which are always written.
+func (o *output) notEmpty(expr string, typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return o.notEmpty(expr, t.X)
+	case *ast.Ident:
+		if o.params[t.Name] {
+			return ""
+		}
+		if o.isNamed(t.Name) {
+			if o.isStruct(t.Name) {
+				return ""
+			}
+			return o.notEmpty(expr, o.underlying(t.Name))
+		}
+		switch basicKind(t.Name) {
+		case "string":
+			return fmt.Sprintf("%s != \"\"", expr)
+		case "bool":
+			return expr
+		case "int", "uint", "float":
+			return fmt.Sprintf("%s != 0", expr)
+		case "error":
+			return fmt.Sprintf("%s != nil", expr)
+		}
+		if t.Name == "any" {
+			return fmt.Sprintf("%s != nil", expr)
+		}
+	case *ast.StarExpr, *ast.InterfaceType, *ast.FuncType, *ast.ChanType:
+		return fmt.Sprintf("%s != nil", expr)
+	case *ast.ArrayType, *ast.MapType:
+		return fmt.Sprintf("len(%s) != 0", expr)
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" && t.Sel.Name == "Duration" {
+			return fmt.Sprintf("%s != 0", expr)
+		}
+	}
+	return ""
+}
+
+// separator writes sep between the fields of an object, omitempty fields are written at run time only,
+// so a field after them writes sep if variable written is set.
+type separator struct {
+	b   *body
+	sep string
+	// some is true once a field is written for sure, maybe once an omitempty field may be written
+	some  bool
+	maybe bool
+}
+
+// field writes a field by write preceded by sep if needed, only if cond is true when it is not empty.
+// more is true if other fields follow it, which need to know whether it is written.
+func (s *separator) field(cond string, more bool, write func()) {
+	if cond != "" {
+		if !s.some && !s.maybe && more {
+			s.b.stmt("written := false")
+		}
+		s.b.stmt(fmt.Sprintf("if %s {", cond))
+	}
+	if s.some {
+		s.b.writeLit(s.sep)
+	} else if s.maybe {
+		s.b.stmt("if written {")
+		s.b.writeLit(s.sep)
+		s.b.stmt("}")
+	}
+	write()
+	if cond == "" {
+		s.some = true
+		return
+	}
+	if !s.some && more {
+		s.b.stmt("written = true")
+		s.maybe = true
+	}
+	s.b.stmt("}")
+}
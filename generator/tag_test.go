@@ -0,0 +1,415 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"io"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructFieldsTag(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A int    `+"`stringer:\"name=a\"`"+`
+	B string `+"`json:\"b\" stringer:\"omitempty,redact\"`"+`
+	C bool   `+"`stringer:\"-\"`"+`
+	d string `+"`stringer:\"-\"`"+`
+	e string `+"`stringer:\"\"`"+`
+}
+`, "")
+	o.fields = "tagged"
+
+	fields := o.structFields(o.specs["MyStruct"].Type.(*ast.StructType))
+	if assert.Len(t, fields, 3) {
+		assert.Equal(t, Field{Name: "A", Type: fields[0].Type, Tag: `stringer:"name=a"`, Key: "a"}, *fields[0])
+		assert.Equal(t, "B", fields[1].Key)
+		assert.True(t, fields[1].OmitEmpty)
+		assert.Equal(t, "full", fields[1].Redact)
+		assert.Equal(t, "e", fields[2].Key)
+	}
+}
+
+func TestNotEmpty(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type Sub struct{}
+
+type Subs []Sub
+`, "")
+
+	for src, want := range map[string]string{
+		"string":        `v != ""`,
+		"bool":          "v",
+		"float32":       "v != 0",
+		"error":         "v != nil",
+		"*Sub":          "v != nil",
+		"[]int":         "len(v) != 0",
+		"map[string]T":  "len(v) != 0",
+		"interface{}":   "v != nil",
+		"Subs":          "len(v) != 0",
+		"time.Duration": "v != 0",
+		"Sub":           "",
+		"time.Time":     "",
+	} {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatalf("parser.ParseExpr() error: %v", err)
+		}
+		assert.Equal(t, want, o.notEmpty("v", expr), src)
+	}
+}
+
+func TestCheckTags(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	A int    ` + "`stringer:\"\"`" + `
+	B string ` + "`stringer:\"redact\"`" + `
+}
+`
+	for method, wantErr := range map[string]string{
+		"codegen": "",
+		"logfmt":  "",
+		"json":    `stringer tag "redact" of MyStruct.B is not supported by method json, use method codegen instead`,
+		"retype":  `stringer tag "redact" of MyStruct.B is not supported by method retype, use method codegen instead`,
+	} {
+		o := parseOutput(t, src, method)
+		err := o.checkTags()
+		if wantErr == "" {
+			assert.NoError(t, err, method)
+		} else {
+			assert.EqualError(t, err, wantErr, method)
+		}
+	}
+}
+
+func TestCheckTagsOmitted(t *testing.T) {
+	// encoding/json does not read the tag, so even a field to omit would be written
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A string `+"`stringer:\"-\"`"+`
+}
+`, "json")
+	assert.EqualError(t, o.checkTags(), `stringer tag "-" of MyStruct.A is not supported by method json, use method codegen instead`)
+}
+
+func TestCheckTagsWarnRedact(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	A string
+	B Sub
+}
+
+type Sub struct {
+	Password string ` + "`stringer:\"redact\"`" + `
+}
+`
+	debug := d.debug
+	d.debug = true
+	defer func() { d.debug = debug }()
+	checkTags := func(method string) string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("os.Pipe() error: %v", err)
+		}
+		stdout := os.Stdout
+		os.Stdout = w
+		defer func() { os.Stdout = stdout }()
+		assert.NoError(t, parseOutput(t, src, method).checkTags(), method)
+		_ = w.Close()
+		out, _ := io.ReadAll(r)
+		return string(out)
+	}
+
+	// a template is not checked to write Field.Redact, the types reaching redacted fields are reported under -v
+	out := checkTags("template")
+	assert.Contains(t, out, "REDACT: MyStruct reaches redacted fields, method template must write them by Field.Redact")
+	assert.Contains(t, out, "REDACT: Sub reaches redacted fields, method template must write them by Field.Redact")
+	assert.NotContains(t, checkTags("codegen"), "REDACT")
+}
+
+func TestCheckTagsUnknownOption(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A string `+"`stringer:\"name=a, omitempty\"`"+`
+	B string `+"`stringer:\"omitempty,redcat\"`"+`
+}
+`, "codegen")
+	assert.EqualError(t, o.checkTags(), "unknown option redcat in stringer tag of MyStruct.B")
+}
+
+func TestCheckRedactedElems(t *testing.T) {
+	src := `
+package main
+
+type Creds struct {
+	User     string
+	Password string
+}
+
+type Account struct {
+	Creds Creds
+}
+
+type CredsList []*Creds
+
+type Login struct {
+	Main    *Creds
+	Backups %s
+}
+`
+	for _, tt := range []struct {
+		method  string
+		extra   string
+		backups string
+		wantErr string
+	}{
+		{method: "logfmt", backups: "[]Creds", wantErr: "elements of Login.Backups have redacted fields, which are not redacted in a slice, array or map by method logfmt, use method codegen instead"},
+		{method: "slog", backups: "map[string]*Creds", wantErr: "elements of Login.Backups have redacted fields, which are not redacted in a slice, array or map by method slog, use method codegen instead"},
+		{method: "slog", backups: "[2]Account", wantErr: "elements of Login.Backups have redacted fields, which are not redacted in a slice, array or map by method slog, use method codegen instead"},
+		{method: "codegen", extra: "formatter", backups: "CredsList", wantErr: "elements of Login.Backups have redacted fields, which are not redacted in a slice, array or map by -formatter"},
+		{method: "codegen", extra: "logvaluer", backups: "*[]Account", wantErr: "elements of Login.Backups have redacted fields, which are not redacted in a slice, array or map by -logvaluer"},
+		{method: "codegen", backups: "[]Creds"},
+		{method: "slog", backups: "[]string"},
+	} {
+		o := parseOutput(t, fmt.Sprintf(src, tt.backups), tt.method)
+		o.redact = []*regexp.Regexp{regexp.MustCompile("^Password$")}
+		o.formatter = tt.extra == "formatter"
+		o.logValuer = tt.extra == "logvaluer"
+		err := o.checkTags()
+		if tt.wantErr == "" {
+			assert.NoError(t, err, tt.backups)
+		} else {
+			assert.EqualError(t, err, tt.wantErr, tt.backups)
+		}
+	}
+}
+
+func TestGenRedactRuns(t *testing.T) {
+	src := `
+package main
+
+type Creds struct {
+	User     string
+	Password string
+}
+
+type Login struct {
+	Main    Creds
+	Backup  *Creds
+	Backups []Creds
+	ByName  map[string]*Creds
+}
+`
+	mainSrc := `
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	c := Creds{User: "gopher", Password: "hunter2"}
+	l := &Login{Main: c, Backup: &c, Backups: []Creds{c}, ByName: map[string]*Creds{"c": &c}}
+	fmt.Println(l)
+	fmt.Printf("%+v %v\n", &c, &c)
+	slog.New(slog.NewTextHandler(os.Stdout, nil)).Info("login", "creds", &c)
+	slog.New(slog.NewJSONHandler(os.Stdout, nil)).Info("login", "creds", &c)
+}
+`
+	o := parseOutput(t, src, "codegen")
+	o.redact = []*regexp.Regexp{regexp.MustCompile("^Password$")}
+	o.formatter, o.logValuer = true, true
+	// Login has redacted elements, which only String writes
+	o.skipDeclared(map[string]map[string]bool{"Login": {"Format": true, "LogValue": true}})
+	res, err := o.gen()
+	assert.NoError(t, err)
+	out := run(t, src, res, mainSrc)
+	assert.Contains(t, out, `"Backups":[{"User":"gopher","Password":"***"}]`)
+	assert.Contains(t, out, "{User:gopher Password:***} {gopher ***}")
+	assert.Contains(t, out, "creds.Password=***")
+	assert.Contains(t, out, `"creds":{"User":"gopher","Password":"***"}`)
+	assert.NotContains(t, out, "hunter2")
+}
+
+func TestGenTag(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	A string ` + "`stringer:\"omitempty\"`" + `
+	B int    ` + "`stringer:\"name=b,omitempty\"`" + `
+	C string ` + "`stringer:\"redact\"`" + `
+	D bool   ` + "`stringer:\"-\"`" + `
+}
+`
+	o := parseOutput(t, src, "codegen")
+	err := genFile(o, "codegen")
+	assert.NoError(t, err)
+	expected := "package main\n" +
+		"\n" +
+		"import (\n" +
+		"\"strconv\"\n" +
+		"\"strings\"\n" +
+		"\n" +
+		"\"github.com/chasemao/stringergen/strgen\"\n" +
+		")\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (m *MyStruct) String() string {\n" +
+		"if m == nil {\n" +
+		"return \"<nil>\"\n" +
+		"}\n" +
+		"sb := &strings.Builder{}\n" +
+		"sb.WriteString(`{`)\n" +
+		"written := false\n" +
+		"if m.A != \"\" {\n" +
+		"sb.WriteString(`\"A\":`)\n" +
+		"strgen.WriteString(sb, m.A)\n" +
+		"written = true\n" +
+		"}\n" +
+		"if m.B != 0 {\n" +
+		"if written {\n" +
+		"sb.WriteString(`,`)\n" +
+		"}\n" +
+		"sb.WriteString(`\"b\":`)\n" +
+		"sb.WriteString(strconv.FormatInt(int64(m.B), 10))\n" +
+		"written = true\n" +
+		"}\n" +
+		"if written {\n" +
+		"sb.WriteString(`,`)\n" +
+		"}\n" +
+		"sb.WriteString(`\"C\":\"***\"}`)\n" +
+		"return sb.String()\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{
+			method: "logfmt",
+			want: []string{
+				"if m.B != 0 {\n\t\tif sb.Len() > 0 {\n\t\t\tsb.WriteString(` `)\n\t\t}\n\t\tsb.WriteString(`b=`)",
+				"if sb.Len() > 0 {\n\t\tsb.WriteString(` `)\n\t}\n\tsb.WriteString(`C=***`)",
+			},
+		},
+		{
+			method: "slog",
+			want: []string{
+				"attrs := make([]slog.Attr, 0, 3)",
+				"if m.B != 0 {\n\t\tattrs = append(attrs, slog.Int64(\"b\", int64(m.B)))\n\t}",
+				`attrs = append(attrs, slog.String("C", "***"))`,
+			},
+		},
+		{
+			method: "zap",
+			want: []string{
+				"if m.A != \"\" {\n\t\tenc.AddString(\"A\", m.A)\n\t}",
+				`enc.AddString("C", "***")`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		o := parseOutput(t, src, tt.method)
+		res, err := o.gen()
+		assert.NoError(t, err)
+		for _, want := range tt.want {
+			assert.Contains(t, string(res), want, tt.method)
+		}
+		assert.NotContains(t, string(res), "m.D", tt.method)
+	}
+
+	o = parseOutput(t, src, "codegen")
+	o.goString = true
+	o.formatter = true
+	res, err := o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), `fmt.Fprintf(st, "{A:%+v b:%+v C:%+v}", m.A, m.B, "***")`)
+	// redacted fields are left out of the literal
+	assert.NotContains(t, string(res), "`C: `")
+}
+
+func TestRedact(t *testing.T) {
+	src := `
+package main
+
+type MyStruct struct {
+	Password  string
+	APIToken  string ` + "`stringer:\"redact=last4\"`" + `
+	Email     string ` + "`stringer:\"redact=sha256\"`" + `
+	Key       string ` + "`stringer:\"redact=len\"`" + `
+	SecretIDs []int
+	Name      string
+}
+`
+	o := parseOutput(t, src, "codegen")
+	o.redact = []*regexp.Regexp{regexp.MustCompile("Password"), regexp.MustCompile("Token|Secret")}
+	var redacts []string
+	for _, f := range o.structFields(o.specs["MyStruct"].Type.(*ast.StructType)) {
+		redacts = append(redacts, f.Redact)
+	}
+	// the tag sets the strategy of a field matching -redact
+	assert.Equal(t, []string{"full", "last4", "sha256", "len", "full", ""}, redacts)
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	for _, want := range []string{
+		"sb.WriteString(`{\"Password\":\"***\",\"APIToken\":`)",
+		"strgen.WriteString(sb, strgen.RedactLast(m.APIToken, 4))",
+		"strgen.WriteString(sb, strgen.RedactHash(m.Email))",
+		"strgen.WriteString(sb, strgen.RedactLen(m.Key))",
+		"sb.WriteString(`,\"SecretIDs\":\"***\",\"Name\":`)",
+	} {
+		assert.Contains(t, string(res), want)
+	}
+
+	o.method = "zap"
+	res, err = o.gen()
+	assert.NoError(t, err)
+	assert.Contains(t, string(res), "\"github.com/chasemao/stringergen/strgen\"")
+	assert.Contains(t, string(res), `enc.AddString("APIToken", strgen.RedactLast(m.APIToken, 4))`)
+	assert.Contains(t, string(res), `enc.AddString("SecretIDs", "***")`)
+
+	o = parseOutput(t, src, "json")
+	o.redact = []*regexp.Regexp{regexp.MustCompile("Password")}
+	_, err = o.gen()
+	assert.EqualError(t, err, "field MyStruct.Password matches -redact, which is not supported by method json, use method codegen instead")
+}
+
+func TestCheckRedact(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	A string `+"`stringer:\"redact=last0\"`"+`
+	B int    `+"`stringer:\"redact=len\"`"+`
+	C int    `+"`stringer:\"redact\"`"+`
+}
+`, "logfmt")
+
+	fields := o.structFields(o.specs["MyStruct"].Type.(*ast.StructType))
+	assert.EqualError(t, o.checkRedact("MyStruct", fields[0]), "unknown redact strategy last0 of MyStruct.A")
+	assert.EqualError(t, o.checkRedact("MyStruct", fields[1]), "redact=len of MyStruct.B needs a string field")
+	assert.NoError(t, o.checkRedact("MyStruct", fields[2]))
+	_, err := o.gen()
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// templateData is what a template of method template is executed with, once for every struct.
+// The imports template is executed with Package and Indent only.
+type templateData struct {
+	Package string
+	Struct  string
+	// TypeArgs are the type parameters of a generic struct like [T], empty for others
+	TypeArgs string
+	Receiver string
+	// Target is the receiver to print or marshal, it is converted to a type without the methods of the struct
+	// like (*UserTarget)(u) if the struct has MarshalJSON, MarshalText, Format or Error methods, which may call String
+	Target string
+	// ValueReceiver is true if String has a value receiver by -receiver=value, the receiver is a pointer otherwise
+	ValueReceiver bool
+	Fields        []*templateField
+	// Indent is the value of -indent flag, String should return multi-line output indented by it if it is not empty
+	Indent string
+	// Fallback is the value of -fallback flag, it decides what String returns when marshal fails
+	Fallback string
+}
+
+// templateField is a Field as templates see it, whose Type is written as in the source like []*Sub.
+type templateField struct {
+	*Field
+	Type string
+}
+
+// templateFuncs can be called from templates besides the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+}
+
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(templateFuncs)
+}
+
+// fallbackTemplate writes what String returns when marshal fails according to -fallback flag,
+// it is included by the json and jsoniter templates.
+const fallbackTemplate = `{{define "fallback"}}
+{{- if eq .Fallback "fmt"}}
+if err != nil {
+return fmt.Sprintf("%+v", *{{.Target}})
+}
+{{- else if ne .Fallback "empty"}}
+if err != nil {
+return {{quote (printf "<%s: marshal error: " .Struct)}} + err.Error() + ">"
+}
+{{- end}}
+{{- end}}`
+
+var (
+	jsonTemplate = template.Must(newTemplate("json").Parse(fallbackTemplate + `{{define "imports"}}"encoding/json"{{end -}}
+{{if .Indent -}}
+v, {{if eq .Fallback "empty"}}_{{else}}err{{end}} := json.MarshalIndent({{.Target}}, "", {{quote .Indent}})
+{{- else -}}
+v, {{if eq .Fallback "empty"}}_{{else}}err{{end}} := json.Marshal({{.Target}})
+{{- end}}
+{{- template "fallback" .}}
+return string(v)`))
+
+	jsonIterTemplate = template.Must(newTemplate("jsoniter").Parse(fallbackTemplate + `{{define "imports"}}jsoniter "github.com/json-iterator/go"{{end -}}
+{{if .Indent -}}
+v, {{if eq .Fallback "empty"}}_{{else}}err{{end}} := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalIndent({{.Target}}, "", {{quote .Indent}})
+{{- else -}}
+v, {{if eq .Fallback "empty"}}_{{else}}err{{end}} := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal({{.Target}})
+{{- end}}
+{{- template "fallback" .}}
+return string(v)`))
+
+	fmtTemplate = template.Must(newTemplate("fmt").Parse(`{{define "imports"}}"fmt"
+{{if .Indent}}"github.com/chasemao/stringergen/strgen"{{end}}{{end -}}
+{{if .Indent -}}
+return strgen.IndentFmt(fmt.Sprintf("%+v", *{{.Target}}), {{quote .Indent}})
+{{- else -}}
+return fmt.Sprintf("%+v",*{{.Target}})
+{{- end}}`))
+)
+
+// parseTemplate parses the template file of -template flag, which method template needs. For other methods
+// it is parsed if it is set, as //stringergen:method=template directives use it, nil is returned otherwise.
+func parseTemplate(method string, path string) (*template.Template, error) {
+	if path == "" {
+		if method != "template" {
+			return nil, nil
+		}
+		return nil, errors.New("method template needs -template flag")
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newTemplate(filepath.Base(path)).Parse(string(text))
+}
+
+// templateMethod writes String methods whose bodies are rendered by t, or by the template
+// of -template flag if t is nil. Import specs are rendered by the template named imports if t defines it, one per line.
+type templateMethod struct {
+	t    *template.Template
+	caps Capabilities
+}
+
+func (m *templateMethod) Capabilities() Capabilities {
+	return m.caps
+}
+
+func (m *templateMethod) template(c *Context) (*template.Template, error) {
+	if m.t != nil {
+		return m.t, nil
+	}
+	if c.o.template == nil {
+		return nil, errors.New("method template needs -template flag")
+	}
+	return c.o.template, nil
+}
+
+func (m *templateMethod) Imports(c *Context) ([]string, error) {
+	t, err := m.template(c)
+	if err != nil {
+		return nil, err
+	}
+	it := t.Lookup("imports")
+	if it == nil {
+		return nil, nil
+	}
+	sb := &strings.Builder{}
+	if err := it.Execute(sb, &templateData{Package: c.o.pkg, Indent: c.o.indent}); err != nil {
+		return nil, fmt.Errorf("failed executing imports template: %v", err)
+	}
+	var imports []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			imports = append(imports, line)
+		}
+	}
+	return imports, nil
+}
+
+func (m *templateMethod) Helpers(c *Context) error {
+	return nil
+}
+
+func (m *templateMethod) Struct(c *Context, name string) error {
+	t, err := m.template(c)
+	if err != nil {
+		return err
+	}
+	data := c.o.templateData(name)
+	sb := &strings.Builder{}
+	if err := t.Execute(sb, data); err != nil {
+		return fmt.Errorf("failed executing template: %v", err)
+	}
+	if data.Target != data.Receiver {
+		c.o.targetType(name)
+	}
+	c.Addln("// String Used in fmt to generate string")
+	c.Addln(fmt.Sprintf("func (%s) String() string {", c.o.recv(name)))
+	c.o.nilGuard(data.Receiver, `"<nil>"`)
+	if c.o.maxLen > 0 {
+		// the template returns from a function literal, whose result is cut
+		c.Addln("return strgen.Truncate(func() string {")
+		c.Addln(strings.TrimSpace(sb.String()))
+		c.Addln(fmt.Sprintf("}(), %d)", c.o.maxLen))
+	} else {
+		c.Addln(strings.TrimSpace(sb.String()))
+	}
+	c.Addln("}")
+	return nil
+}
+
+func (o *output) templateData(name string) *templateData {
+	data := &templateData{
+		Package:       o.pkg,
+		Struct:        name,
+		TypeArgs:      o.typeArgs(name),
+		Receiver:      strings.ToLower(name[0:1]),
+		Target:        strings.ToLower(name[0:1]),
+		ValueReceiver: o.valueReceiver(),
+		Indent:        o.indent,
+		Fallback:      o.fallback,
+	}
+	if m := o.hazard(name); m != "" {
+		d.Printf(yellow+"HAZARD: %s has method %s which may call String, %sTarget without it is printed"+reset, name, m, name)
+		data.Target = o.target(name)
+	}
+	st := o.structType(name)
+	if st == nil {
+		return data
+	}
+	for _, f := range o.structFields(st) {
+		data.Fields = append(data.Fields, &templateField{Field: f, Type: types.ExprString(f.Type)})
+	}
+	return data
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplate(t *testing.T) {
+	_, err := parseTemplate("template", "")
+	assert.Error(t, err)
+
+	tmpl, err := parseTemplate("json", "")
+	assert.NoError(t, err)
+	assert.Nil(t, tmpl)
+
+	_, err = parseTemplate("json", filepath.Join(t.TempDir(), "missing.tmpl"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "bad.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Struct"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = parseTemplate("template", path)
+	assert.Error(t, err)
+}
+
+func TestGenTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "string.tmpl")
+	if err := os.WriteFile(path, []byte(`{{define "imports"}}
+"fmt"
+"strings"
+{{end}}
+parts := []string{ {{- range .Fields}}
+	fmt.Sprintf("%s=%v", {{quote (or (.TagValue "log") .Name)}}, {{$.Receiver}}.{{.Name}}), // {{.Type}}
+{{- end}}
+}
+return {{quote .Package}} + "." + {{quote .Struct}} + "{" + strings.Join(parts, " ") + "}"
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseTemplate("template", path)
+	if err != nil {
+		t.Fatalf("parseTemplate() error: %v", err)
+	}
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	ID   int    `+"`log:\"id\"`"+`
+	Subs []*Sub
+	*Sub
+	hidden string
+}
+
+type Sub struct{}
+`, "template")
+	o.structNames = o.structNames[:1]
+	o.template = tmpl
+
+	err = genFile(o, "template")
+	assert.NoError(t, err)
+
+	expected := "package main\n" +
+		"\n" +
+		"import (\n" +
+		"\"fmt\"\n" +
+		"\"strings\"\n" +
+		")\n" +
+		"\n" +
+		"// String Used in fmt to generate string\n" +
+		"func (m *MyStruct) String() string {\n" +
+		"if m == nil {\n" +
+		"return \"<nil>\"\n" +
+		"}\n" +
+		"parts := []string{\n" +
+		"\tfmt.Sprintf(\"%s=%v\", \"id\", m.ID), // int\n" +
+		"\tfmt.Sprintf(\"%s=%v\", \"Subs\", m.Subs), // []*Sub\n" +
+		"\tfmt.Sprintf(\"%s=%v\", \"Sub\", m.Sub), // *Sub\n" +
+		"}\n" +
+		"return \"main\" + \".\" + \"MyStruct\" + \"{\" + strings.Join(parts, \" \") + \"}\"\n" +
+		"}\n"
+	assert.Equal(t, expected, o.buf.String())
+
+	_, err = o.gen()
+	assert.NoError(t, err)
+}
+
+func TestGenTemplateError(t *testing.T) {
+	tmpl, err := newTemplate("error").Parse("{{.Missing}}")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	o := &output{
+		pkg:         "main",
+		structNames: []string{"MyStruct"},
+		method:      "template",
+		template:    tmpl,
+	}
+
+	_, err = o.gen()
+	assert.Error(t, err)
+}
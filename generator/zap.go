@@ -0,0 +1,274 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// zapMarshaler returns the zapcore marshaler implemented by the method of type name, which is object for structs
+// and maps with string keys, array for slices and arrays, empty for types without one.
+func (o *output) zapMarshaler(name string) string {
+	switch t := o.underlying(name).(type) {
+	case *ast.StructType:
+		return "object"
+	case *ast.ArrayType:
+		return "array"
+	case *ast.MapType:
+		if id, ok := t.Key.(*ast.Ident); ok && id.Name == "string" {
+			return "object"
+		}
+	}
+	return ""
+}
+
+// zapMarshals reports whether type name gets a zapcore marshaler method.
+func (o *output) zapMarshals(name string) bool {
+	return o.zapMarshaler(name) != ""
+}
+
+// genZap writes a MarshalLogObject method for structs and maps with string keys,
+// or a MarshalLogArray method for slices and arrays.
+func (o *output) genZap(name string) {
+	n := strings.ToLower(name[0:1])
+	switch t := o.underlying(name).(type) {
+	case *ast.ArrayType:
+		o.addln("// MarshalLogArray Used in zap to encode elements without reflection")
+		o.addln(fmt.Sprintf("func (%s) MarshalLogArray(arr zapcore.ArrayEncoder) error {", o.recv(name)))
+		o.nilGuard(n, "nil")
+		// the closures of nested slices and maps are numbered from 1
+		z := &zapgen{o: o, depth: 1}
+		o.addln(fmt.Sprintf("for _, v0 := range %s {", o.deref(n)))
+		z.appendElem("arr", "v0", t.Elt)
+		o.addln("}")
+		o.addln("return nil")
+		o.addln("}")
+		return
+	case *ast.MapType:
+		o.addln("// MarshalLogObject Used in zap to encode fields without reflection")
+		o.addln(fmt.Sprintf("func (%s) MarshalLogObject(enc zapcore.ObjectEncoder) error {", o.recv(name)))
+		o.nilGuard(n, "nil")
+		z := &zapgen{o: o, depth: 1}
+		o.addln(fmt.Sprintf("for k0, v0 := range %s {", o.deref(n)))
+		z.add("enc", "k0", "v0", t.Value)
+		o.addln("}")
+		o.addln("return nil")
+		o.addln("}")
+		return
+	}
+	o.addln("// MarshalLogObject Used in zap to encode fields without reflection")
+	o.addln(fmt.Sprintf("func (%s) MarshalLogObject(enc zapcore.ObjectEncoder) error {", o.recv(name)))
+	o.nilGuard(n, "nil")
+	z := &zapgen{o: o}
+	for _, f := range o.structFields(o.structType(name)) {
+		cond := ""
+		if f.OmitEmpty {
+			cond = o.notEmpty(n+"."+f.Name, f.Type)
+		}
+		if cond != "" {
+			o.addln(fmt.Sprintf("if %s {", cond))
+		}
+		if f.Redact != "" {
+			o.addln(fmt.Sprintf("enc.AddString(%q, %s)", f.Key, f.redaction(n+"."+f.Name)))
+		} else {
+			z.add("enc", fmt.Sprintf("%q", f.Key), n+"."+f.Name, f.Type)
+		}
+		if cond != "" {
+			o.addln("}")
+		}
+	}
+	o.addln("return nil")
+	o.addln("}")
+}
+
+// zapAdd and zapAppend are the encoder methods adding and appending a value by its zapcore marshaler.
+var (
+	zapAdd    = map[string]string{"object": "AddObject", "array": "AddArray"}
+	zapAppend = map[string]string{"object": "AppendObject", "array": "AppendArray"}
+)
+
+// zapgen writes typed zapcore encoder calls, slices and maps are encoded by
+// ArrayMarshalerFunc and ObjectMarshalerFunc closures so that no reflection is needed.
+type zapgen struct {
+	o     *output
+	depth int
+}
+
+// check writes call which returns an error, the error is returned from the enclosing function.
+func (z *zapgen) check(call string) {
+	z.o.addln(fmt.Sprintf("if err := %s; err != nil {", call))
+	z.o.addln("return err")
+	z.o.addln("}")
+}
+
+// add writes expr whose type is typ to the object encoder enc with key.
+func (z *zapgen) add(enc string, key string, expr string, typ ast.Expr) {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		z.add(enc, key, expr, t.X)
+		return
+	case *ast.Ident:
+		if m := z.o.zapMarshaler(t.Name); m != "" {
+			z.check(fmt.Sprintf("%s.%s(%s, &%s)", enc, zapAdd[m], key, expr))
+			return
+		}
+		switch basicKind(t.Name) {
+		case "string":
+			z.o.addln(fmt.Sprintf("%s.AddString(%s, %s)", enc, key, expr))
+			return
+		case "bool":
+			z.o.addln(fmt.Sprintf("%s.AddBool(%s, %s)", enc, key, expr))
+			return
+		case "int":
+			z.o.addln(fmt.Sprintf("%s.AddInt64(%s, int64(%s))", enc, key, expr))
+			return
+		case "uint":
+			z.o.addln(fmt.Sprintf("%s.AddUint64(%s, uint64(%s))", enc, key, expr))
+			return
+		case "float":
+			if t.Name == "float32" {
+				z.o.addln(fmt.Sprintf("%s.AddFloat32(%s, %s)", enc, key, expr))
+			} else {
+				z.o.addln(fmt.Sprintf("%s.AddFloat64(%s, %s)", enc, key, expr))
+			}
+			return
+		case "error":
+			z.o.addln(fmt.Sprintf("if %s != nil {", expr))
+			z.o.addln(fmt.Sprintf("%s.AddString(%s, %s)", enc, key, sel(expr, "Error()")))
+			z.o.addln("}")
+			return
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" {
+			switch t.Sel.Name {
+			case "Time":
+				z.o.addln(fmt.Sprintf("%s.AddTime(%s, %s)", enc, key, expr))
+				return
+			case "Duration":
+				z.o.addln(fmt.Sprintf("%s.AddDuration(%s, %s)", enc, key, expr))
+				return
+			}
+		}
+	case *ast.StarExpr:
+		z.o.addln(fmt.Sprintf("if %s != nil {", expr))
+		if id, ok := t.X.(*ast.Ident); ok && z.o.zapMarshals(id.Name) {
+			z.check(fmt.Sprintf("%s.%s(%s, %s)", enc, zapAdd[z.o.zapMarshaler(id.Name)], key, expr))
+		} else {
+			z.add(enc, key, "*"+expr, t.X)
+		}
+		z.o.addln("}")
+		return
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && (id.Name == "byte" || id.Name == "uint8") {
+			z.o.addln(fmt.Sprintf("%s.AddBinary(%s, %s[:])", enc, key, expr))
+			return
+		}
+		z.arrayMarshaler(fmt.Sprintf("%s.AddArray(%s, ", enc, key), expr, t)
+		return
+	case *ast.MapType:
+		if id, ok := t.Key.(*ast.Ident); ok && id.Name == "string" {
+			z.objectMarshaler(fmt.Sprintf("%s.AddObject(%s, ", enc, key), expr, t)
+			return
+		}
+	}
+	z.check(fmt.Sprintf("%s.AddReflected(%s, %s)", enc, key, expr))
+}
+
+// appendElem writes expr whose type is typ to the array encoder arr.
+func (z *zapgen) appendElem(arr string, expr string, typ ast.Expr) {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		z.appendElem(arr, expr, t.X)
+		return
+	case *ast.Ident:
+		if m := z.o.zapMarshaler(t.Name); m != "" {
+			z.check(fmt.Sprintf("%s.%s(&%s)", arr, zapAppend[m], expr))
+			return
+		}
+		switch basicKind(t.Name) {
+		case "string":
+			z.o.addln(fmt.Sprintf("%s.AppendString(%s)", arr, expr))
+			return
+		case "bool":
+			z.o.addln(fmt.Sprintf("%s.AppendBool(%s)", arr, expr))
+			return
+		case "int":
+			z.o.addln(fmt.Sprintf("%s.AppendInt64(int64(%s))", arr, expr))
+			return
+		case "uint":
+			z.o.addln(fmt.Sprintf("%s.AppendUint64(uint64(%s))", arr, expr))
+			return
+		case "float":
+			if t.Name == "float32" {
+				z.o.addln(fmt.Sprintf("%s.AppendFloat32(%s)", arr, expr))
+			} else {
+				z.o.addln(fmt.Sprintf("%s.AppendFloat64(%s)", arr, expr))
+			}
+			return
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" {
+			switch t.Sel.Name {
+			case "Time":
+				z.o.addln(fmt.Sprintf("%s.AppendTime(%s)", arr, expr))
+				return
+			case "Duration":
+				z.o.addln(fmt.Sprintf("%s.AppendDuration(%s)", arr, expr))
+				return
+			}
+		}
+	case *ast.StarExpr:
+		z.o.addln(fmt.Sprintf("if %s == nil {", expr))
+		z.check(fmt.Sprintf("%s.AppendReflected(nil)", arr))
+		z.o.addln("} else {")
+		if id, ok := t.X.(*ast.Ident); ok && z.o.zapMarshals(id.Name) {
+			z.check(fmt.Sprintf("%s.%s(%s)", arr, zapAppend[z.o.zapMarshaler(id.Name)], expr))
+		} else {
+			z.appendElem(arr, "*"+expr, t.X)
+		}
+		z.o.addln("}")
+		return
+	case *ast.ArrayType:
+		z.arrayMarshaler(arr+".AppendArray(", expr, t)
+		return
+	case *ast.MapType:
+		if id, ok := t.Key.(*ast.Ident); ok && id.Name == "string" {
+			z.objectMarshaler(arr+".AppendObject(", expr, t)
+			return
+		}
+	}
+	z.check(fmt.Sprintf("%s.AppendReflected(%s)", arr, expr))
+}
+
+// arrayMarshaler writes call whose last argument is an ArrayMarshalerFunc closure encoding expr,
+// call is like enc.AddArray("key", and the error it returns is checked.
+func (z *zapgen) arrayMarshaler(call string, expr string, t *ast.ArrayType) {
+	arr, v := fmt.Sprintf("arr%d", z.depth), fmt.Sprintf("v%d", z.depth)
+	z.closure(fmt.Sprintf("%szapcore.ArrayMarshalerFunc(func(%s zapcore.ArrayEncoder) error {", call, arr), func() {
+		z.o.addln(fmt.Sprintf("for _, %s := range %s {", v, expr))
+		z.appendElem(arr, v, t.Elt)
+		z.o.addln("}")
+	})
+}
+
+// objectMarshaler writes call whose last argument is an ObjectMarshalerFunc closure encoding map expr,
+// call is like enc.AddObject("key", and the error it returns is checked.
+func (z *zapgen) objectMarshaler(call string, expr string, t *ast.MapType) {
+	enc, k, v := fmt.Sprintf("enc%d", z.depth), fmt.Sprintf("k%d", z.depth), fmt.Sprintf("v%d", z.depth)
+	z.closure(fmt.Sprintf("%szapcore.ObjectMarshalerFunc(func(%s zapcore.ObjectEncoder) error {", call, enc), func() {
+		z.o.addln(fmt.Sprintf("for %s, %s := range %s {", k, v, expr))
+		z.add(enc, k, v, t.Value)
+		z.o.addln("}")
+	})
+}
+
+func (z *zapgen) closure(head string, body func()) {
+	z.depth++
+	defer func() { z.depth-- }()
+	z.o.addln("if err := " + head)
+	body()
+	z.o.addln("return nil")
+	z.o.addln("})); err != nil {")
+	z.o.addln("return err")
+	z.o.addln("}")
+}
@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenZap(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type MyStruct struct {
+	Field1 string
+	Field2 *Sub
+	Field3 []int
+	Field4 map[string]Sub
+	Field5 map[int]int
+}
+
+type Sub struct{}
+`, "zap")
+	o.structNames = o.structNames[:1]
+
+	err := genFile(o, "zap")
+	assert.NoError(t, err)
+
+	expected := `package main
+
+import (
+"go.uber.org/zap/zapcore"
+)
+
+// MarshalLogObject Used in zap to encode fields without reflection
+func (m *MyStruct) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+if m == nil {
+return nil
+}
+enc.AddString("Field1", m.Field1)
+if m.Field2 != nil {
+if err := enc.AddObject("Field2", m.Field2); err != nil {
+return err
+}
+}
+if err := enc.AddArray("Field3", zapcore.ArrayMarshalerFunc(func(arr0 zapcore.ArrayEncoder) error {
+for _, v0 := range m.Field3 {
+arr0.AppendInt64(int64(v0))
+}
+return nil
+})); err != nil {
+return err
+}
+if err := enc.AddObject("Field4", zapcore.ObjectMarshalerFunc(func(enc0 zapcore.ObjectEncoder) error {
+for k0, v0 := range m.Field4 {
+if err := enc0.AddObject(k0, &v0); err != nil {
+return err
+}
+}
+return nil
+})); err != nil {
+return err
+}
+if err := enc.AddReflected("Field5", m.Field5); err != nil {
+return err
+}
+return nil
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+}
+
+func TestGenZapCompiles(t *testing.T) {
+	src := `
+package main
+
+import "time"
+
+type MyStruct struct {
+	A [][]*MyStruct
+	B map[string][]map[string]*int
+	C [4]byte
+	D time.Time
+	E error
+	F Users
+	G *Index
+}
+
+type Users []*MyStruct
+
+type Index map[string]Users
+`
+	o := parseOutput(t, src, "zap")
+
+	res, err := o.gen()
+	assert.NoError(t, err)
+	typeCheck(t, src, res)
+}
+
+func TestGenZapNamedTypes(t *testing.T) {
+	o := parseOutput(t, `
+package main
+
+type Users []*User
+
+type Index map[string]Users
+
+type Ranks map[int]string
+
+type User struct {
+	Name string
+	Tags Tags
+}
+
+type Tags []string
+`, "zap")
+
+	err := genFile(o, "zap")
+	assert.NoError(t, err)
+
+	// Ranks has no string keys, it is skipped and leaves an empty line, which gofmt removes
+	expected := `package main
+
+import (
+"go.uber.org/zap/zapcore"
+)
+
+// MarshalLogArray Used in zap to encode elements without reflection
+func (u *Users) MarshalLogArray(arr zapcore.ArrayEncoder) error {
+if u == nil {
+return nil
+}
+for _, v0 := range *u {
+if v0 == nil {
+if err := arr.AppendReflected(nil); err != nil {
+return err
+}
+} else {
+if err := arr.AppendObject(v0); err != nil {
+return err
+}
+}
+}
+return nil
+}
+
+// MarshalLogObject Used in zap to encode fields without reflection
+func (i *Index) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+if i == nil {
+return nil
+}
+for k0, v0 := range *i {
+if err := enc.AddArray(k0, &v0); err != nil {
+return err
+}
+}
+return nil
+}
+
+
+// MarshalLogObject Used in zap to encode fields without reflection
+func (u *User) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+if u == nil {
+return nil
+}
+enc.AddString("Name", u.Name)
+if err := enc.AddArray("Tags", &u.Tags); err != nil {
+return err
+}
+return nil
+}
+
+// MarshalLogArray Used in zap to encode elements without reflection
+func (t *Tags) MarshalLogArray(arr zapcore.ArrayEncoder) error {
+if t == nil {
+return nil
+}
+for _, v0 := range *t {
+arr.AppendString(v0)
+}
+return nil
+}
+`
+	assert.Equal(t, expected, o.buf.String())
+}
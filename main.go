@@ -0,0 +1,8 @@
+// StringerGen generates string implementations of Go structs.
+package main
+
+import "github.com/chasemao/stringergen/generator"
+
+func main() {
+	generator.Main()
+}
@@ -0,0 +1,101 @@
+package strgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// IndentJSON returns JSON s with every element on its own line, indented by indent like json.MarshalIndent.
+// s is returned as is if it is not valid JSON.
+func IndentJSON(s string, indent string) string {
+	b := &bytes.Buffer{}
+	if err := json.Indent(b, []byte(s), "", indent); err != nil {
+		return s
+	}
+	return b.String()
+}
+
+// IndentFmt returns s printed by %+v with every struct field on its own line, indented by indent.
+// Slices and maps are kept in one line, structs in them are expanded.
+// It is a best effort layout, a string value which looks like " Name:" is broken as if it is a field.
+func IndentFmt(s string, indent string) string {
+	s = compactFmt(s)
+	b := &strings.Builder{}
+	// expanded holds an entry for every open bracket, true for a struct whose fields are on their own lines
+	var expanded []bool
+	depth := 0
+	newline := func() {
+		b.WriteByte('\n')
+		b.WriteString(strings.Repeat(indent, depth))
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '{':
+			b.WriteByte(c)
+			expand := isFieldName(s[i+1:])
+			expanded = append(expanded, expand)
+			if expand {
+				depth++
+				newline()
+			}
+		case c == '[':
+			b.WriteByte(c)
+			expanded = append(expanded, false)
+		case (c == '}' || c == ']') && len(expanded) > 0:
+			if expanded[len(expanded)-1] {
+				depth--
+				newline()
+			}
+			expanded = expanded[:len(expanded)-1]
+			b.WriteByte(c)
+		case c == ' ' && len(expanded) > 0 && expanded[len(expanded)-1] && isFieldName(s[i+1:]):
+			newline()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compactFmt removes the line breaks written by IndentFmt, so that String of nested structs is laid out again.
+func compactFmt(s string) string {
+	if !strings.Contains(s, "\n") {
+		return s
+	}
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\n' {
+			b.WriteByte(s[i])
+			continue
+		}
+		for i+1 < len(s) && (s[i+1] == ' ' || s[i+1] == '\t') {
+			i++
+		}
+		str := b.String()
+		if (len(str) == 0 || str[len(str)-1] != '{') && (i+1 >= len(s) || s[i+1] != '}') {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// isFieldName reports whether s starts with a field name and a colon like Name:.
+func isFieldName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ':':
+			return i > 0
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
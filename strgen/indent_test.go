@@ -0,0 +1,64 @@
+package strgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndentJSON(t *testing.T) {
+	assert.Equal(t, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {}\n}", IndentJSON(`{"a":[1,2],"b":{}}`, "  "))
+	// nested String methods are indented already
+	assert.Equal(t, "{\n\t\"a\": {\n\t\t\"b\": 1\n\t}\n}", IndentJSON("{\"a\":{\n  \"b\": 1\n}}", "\t"))
+	assert.Equal(t, "not json", IndentJSON("not json", "  "))
+}
+
+func TestIndentFmt(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "Flat",
+			in:   "{A:1 B:a b}",
+			want: "{\n  A:1\n  B:a b\n}",
+		},
+		{
+			name: "Empty",
+			in:   "{}",
+			want: "{}",
+		},
+		{
+			name: "Nested",
+			in:   "{A:{B:1 C:<nil>} D:2}",
+			want: "{\n  A:{\n    B:1\n    C:<nil>\n  }\n  D:2\n}",
+		},
+		{
+			name: "Slice and map",
+			in:   "{A:[1 2] B:map[k:v x:y] C:[{D:1}]}",
+			want: "{\n  A:[1 2]\n  B:map[k:v x:y]\n  C:[{\n    D:1\n  }]\n}",
+		},
+		{
+			name: "Time",
+			in:   "{A:1970-01-01 00:00:00 +0000 UTC B:1}",
+			want: "{\n  A:1970-01-01 00:00:00 +0000 UTC\n  B:1\n}",
+		},
+		{
+			name: "Nested String method",
+			in:   "{A:{\n  B:1\n  C:2\n} D:3}",
+			want: "{\n  A:{\n    B:1\n    C:2\n  }\n  D:3\n}",
+		},
+		{
+			name: "Not struct",
+			in:   "a b",
+			want: "a b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IndentFmt(tt.in, "  "))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package strgen
+
+import (
+	"bytes"
+	"slices"
+	"strconv"
+)
+
+// StringKeys returns the keys of m sorted, in the order encoding/json writes them.
+func StringKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// IntKeys returns the keys of m sorted by their decimal form like "10" before "9",
+// in the order encoding/json writes them.
+func IntKeys[K ~int | ~int8 | ~int16 | ~int32 | ~int64, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b K) int {
+		var x, y [20]byte
+		return bytes.Compare(strconv.AppendInt(x[:0], int64(a), 10), strconv.AppendInt(y[:0], int64(b), 10))
+	})
+	return keys
+}
+
+// UintKeys returns the keys of m sorted by their decimal form like "10" before "9",
+// in the order encoding/json writes them.
+func UintKeys[K ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b K) int {
+		var x, y [20]byte
+		return bytes.Compare(strconv.AppendUint(x[:0], uint64(a), 10), strconv.AppendUint(y[:0], uint64(b), 10))
+	})
+	return keys
+}
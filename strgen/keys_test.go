@@ -0,0 +1,23 @@
+package strgen
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeys(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, StringKeys(map[string]int{"c": 3, "a": 1, "b": 2}))
+	assert.Equal(t, []string{}, StringKeys(map[string]int(nil)))
+
+	ints := map[int]bool{9: true, 10: true, -1: true, 100: true}
+	assert.Equal(t, []int{-1, 10, 100, 9}, IntKeys(ints))
+	uints := map[uint8]bool{9: true, 10: true, 255: true}
+	assert.Equal(t, []uint8{10, 255, 9}, UintKeys(uints))
+
+	// the order is the one of encoding/json
+	v, err := json.Marshal(ints)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"-1":true,"10":true,"100":true,"9":true}`, string(v))
+}
@@ -0,0 +1,31 @@
+package strgen
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Truncate returns s cut to at most n bytes followed by "..." if it is longer than n bytes,
+// it is cut at the start of a rune so that the result is valid UTF-8 if s is.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
+
+// WriteMore writes the JSON string "... n more" to b, which takes the place of n elided elements.
+func WriteMore(b *strings.Builder, n int) {
+	b.Write(AppendMore(nil, n))
+}
+
+// AppendMore appends the JSON string "... n more" to dst and returns the extended buffer.
+func AppendMore(dst []byte, n int) []byte {
+	dst = append(dst, `"... `...)
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	return append(dst, ` more"`...)
+}
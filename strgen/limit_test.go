@@ -0,0 +1,22 @@
+package strgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", Truncate("abc", 3))
+	assert.Equal(t, "ab...", Truncate("abc", 2))
+	assert.Equal(t, "...", Truncate("abc", 0))
+	// é is two bytes, it is not cut in the middle
+	assert.Equal(t, "a...", Truncate("aéb", 2))
+}
+
+func TestWriteMore(t *testing.T) {
+	sb := &strings.Builder{}
+	WriteMore(sb, 42)
+	assert.Equal(t, `"... 42 more"`, sb.String())
+}
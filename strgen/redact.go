@@ -0,0 +1,40 @@
+package strgen
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Redacted is written in place of a redacted value.
+const Redacted = "***"
+
+// RedactLast returns *** followed by the last n runes of s, or *** only if s has n runes or less,
+// so that a short secret is not shown entirely.
+func RedactLast(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return Redacted
+	}
+	i := len(s)
+	for ; n > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return Redacted + s[i:]
+}
+
+// RedactLen returns *** followed by the length of s in bytes like ***(12).
+func RedactLen(s string) string {
+	return Redacted + "(" + strconv.Itoa(len(s)) + ")"
+}
+
+// RedactHash returns sha256: followed by the first 8 hex digits of the SHA-256 of s,
+// equal values have the same hash so that they can be matched without being shown.
+func RedactHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	dst := []byte("sha256:")
+	for _, b := range sum[:4] {
+		dst = append(dst, hex[b>>4], hex[b&0xf])
+	}
+	return string(dst)
+}
@@ -0,0 +1,27 @@
+package strgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactLast(t *testing.T) {
+	assert.Equal(t, "***6789", RedactLast("0123456789", 4))
+	assert.Equal(t, "***ü", RedactLast("aü", 1))
+	// the whole value is not shown
+	assert.Equal(t, "***", RedactLast("6789", 4))
+	assert.Equal(t, "***", RedactLast("", 0))
+}
+
+func TestRedactLen(t *testing.T) {
+	assert.Equal(t, "***(6)", RedactLen("secret"))
+	assert.Equal(t, "***(0)", RedactLen(""))
+}
+
+func TestRedactHash(t *testing.T) {
+	// sha256("secret") is 2bb80d53...
+	assert.Equal(t, "sha256:2bb80d53", RedactHash("secret"))
+	assert.Equal(t, RedactHash("a"), RedactHash("a"))
+	assert.NotEqual(t, RedactHash("a"), RedactHash("b"))
+}
@@ -0,0 +1,171 @@
+// Package strgen holds the runtime helpers used by String methods generated by stringergen.
+package strgen
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// WriteString writes s to b as a quoted JSON string, it writes to b directly so that it does not allocate
+// for every field like AppendString with a nil buffer would.
+func WriteString(b *strings.Builder, s string) {
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	// buf holds an escape sequence on the stack
+	var buf [8]byte
+	start := 0
+	for i := 0; i < len(s); {
+		if plain(s[i]) {
+			i++
+			continue
+		}
+		esc, size := escape(buf[:0], s[i:])
+		if len(esc) > 0 {
+			b.WriteString(s[start:i])
+			b.Write(esc)
+			start = i + size
+		}
+		i += size
+	}
+	b.WriteString(s[start:])
+	b.WriteByte('"')
+}
+
+// WriteFloat writes f to b as a JSON number, NaN and infinities are written as quoted strings.
+func WriteFloat(b *strings.Builder, f float64, bitSize int) {
+	// the longest float is 24 bytes, so buf keeps it on the stack
+	var buf [32]byte
+	b.Write(AppendFloat(buf[:0], f, bitSize))
+}
+
+// WriteJSON writes v to b using encoding/json, it is used for values the generator does not know how to handle.
+// If marshal fails, the error is written as a quoted JSON string.
+func WriteJSON(b *strings.Builder, v interface{}) {
+	b.Write(AppendJSON(nil, v))
+}
+
+// WriteLogfmt writes s to b as a logfmt value, it is quoted when it is empty or contains
+// spaces, '=', '"' or characters that are not printable.
+func WriteLogfmt(b *strings.Builder, s string) {
+	if needQuote(s) {
+		b.WriteString(strconv.Quote(s))
+		return
+	}
+	b.WriteString(s)
+}
+
+func needQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendString appends s to dst as a quoted JSON string and returns the extended buffer.
+func AppendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	var buf [8]byte
+	start := 0
+	for i := 0; i < len(s); {
+		if plain(s[i]) {
+			i++
+			continue
+		}
+		esc, size := escape(buf[:0], s[i:])
+		if len(esc) > 0 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, esc...)
+			start = i + size
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// plain reports whether c is an ASCII byte written as is in a JSON string.
+func plain(c byte) bool {
+	return c < utf8.RuneSelf && c >= 0x20 && c != '"' && c != '\\'
+}
+
+// escape appends the escape sequence of the first character of s to dst, nothing if it is written as is.
+// It returns the extended buffer and the size of the character in s.
+func escape(dst []byte, s string) ([]byte, int) {
+	if c := s[0]; c < utf8.RuneSelf {
+		switch c {
+		case '"', '\\':
+			return append(dst, '\\', c), 1
+		case '\n':
+			return append(dst, '\\', 'n'), 1
+		case '\r':
+			return append(dst, '\\', 'r'), 1
+		case '\t':
+			return append(dst, '\\', 't'), 1
+		}
+		if c < 0x20 {
+			return append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf]), 1
+		}
+		return dst, 1
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	switch {
+	case r == utf8.RuneError && size == 1:
+		return append(dst, "\ufffd"...), size
+	case r == '\u2028' || r == '\u2029':
+		// U+2028 and U+2029 are valid JSON but break JavaScript, escape them like encoding/json does
+		return append(dst, '\\', 'u', '2', '0', '2', hex[r&0xf]), size
+	}
+	return dst, size
+}
+
+// AppendFloat appends f to dst as a JSON number and returns the extended buffer,
+// NaN and infinities are appended as quoted strings.
+func AppendFloat(dst []byte, f float64, bitSize int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		dst = append(dst, '"')
+		dst = strconv.AppendFloat(dst, f, 'g', -1, bitSize)
+		return append(dst, '"')
+	}
+	return strconv.AppendFloat(dst, f, 'g', -1, bitSize)
+}
+
+// GoFloat returns f as a Go expression of a float of bitSize bits, NaN and infinities, which have no literals,
+// are written as math.NaN() and math.Inf(1) converted to float32 if bitSize is 32.
+func GoFloat(f float64, bitSize int) string {
+	var expr string
+	switch {
+	case math.IsNaN(f):
+		expr = "math.NaN()"
+	case math.IsInf(f, 1):
+		expr = "math.Inf(1)"
+	case math.IsInf(f, -1):
+		expr = "math.Inf(-1)"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+	if bitSize == 32 {
+		return "float32(" + expr + ")"
+	}
+	return expr
+}
+
+// AppendJSON appends v to dst using encoding/json and returns the extended buffer.
+// If marshal fails, the error is appended as a quoted JSON string.
+func AppendJSON(dst []byte, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return AppendString(dst, "marshal error: "+err.Error())
+	}
+	return append(dst, data...)
+}
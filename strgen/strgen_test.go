@@ -0,0 +1,197 @@
+package strgen
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "Plain",
+			in:   "abc",
+			want: `"abc"`,
+		},
+		{
+			name: "Empty",
+			in:   "",
+			want: `""`,
+		},
+		{
+			name: "Quote and backslash",
+			in:   `a"b\c`,
+			want: `"a\"b\\c"`,
+		},
+		{
+			name: "Control characters",
+			in:   "a\nb\tc\x01",
+			want: `"a\nb\tc\u0001"`,
+		},
+		{
+			name: "Unicode",
+			in:   "中文",
+			want: `"中文"`,
+		},
+		{
+			name: "Invalid utf8",
+			in:   "a\xffb",
+			want: "\"a\ufffdb\"",
+		},
+		{
+			name: "Line separator",
+			in:   "a\u2028b",
+			want: `"a\u2028b"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &strings.Builder{}
+			WriteString(b, tt.in)
+			assert.Equal(t, tt.want, b.String())
+			assert.True(t, json.Valid([]byte(b.String())))
+			assert.Equal(t, tt.want, string(AppendString(nil, tt.in)))
+		})
+	}
+}
+
+func TestWriteFloat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   float64
+		want string
+	}{
+		{
+			name: "Integer",
+			in:   1,
+			want: "1",
+		},
+		{
+			name: "Fraction",
+			in:   12312312.12321,
+			want: "1.231231212321e+07",
+		},
+		{
+			name: "NaN",
+			in:   math.NaN(),
+			want: `"NaN"`,
+		},
+		{
+			name: "Inf",
+			in:   math.Inf(-1),
+			want: `"-Inf"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &strings.Builder{}
+			WriteFloat(b, tt.in, 64)
+			assert.Equal(t, tt.want, b.String())
+			assert.True(t, json.Valid([]byte(b.String())))
+		})
+	}
+}
+
+func TestWriteAllocs(t *testing.T) {
+	b := &strings.Builder{}
+	b.Grow(1024)
+	allocs := testing.AllocsPerRun(10, func() {
+		WriteString(b, "a\"b\n中文")
+		WriteFloat(b, 1.5, 64)
+		WriteFloat(b, math.Inf(1), 64)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestWriteJSON(t *testing.T) {
+	b := &strings.Builder{}
+	WriteJSON(b, map[string]int{"a": 1})
+	assert.Equal(t, `{"a":1}`, b.String())
+
+	b = &strings.Builder{}
+	WriteJSON(b, make(chan int))
+	assert.Equal(t, `"marshal error: json: unsupported type: chan int"`, b.String())
+}
+
+func TestAppend(t *testing.T) {
+	dst := []byte("[")
+	dst = AppendString(dst, "a\"b")
+	dst = append(dst, ',')
+	dst = AppendFloat(dst, 1.5, 64)
+	dst = append(dst, ',')
+	dst = AppendFloat(dst, math.NaN(), 64)
+	dst = append(dst, ',')
+	dst = AppendJSON(dst, []int{1})
+	dst = append(dst, ']')
+	assert.Equal(t, `["a\"b",1.5,"NaN",[1]]`, string(dst))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = AppendString(dst[:0], "abc")
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestGoFloat(t *testing.T) {
+	assert.Equal(t, "1.5", GoFloat(1.5, 64))
+	assert.Equal(t, "1e+21", GoFloat(1e21, 64))
+	assert.Equal(t, "0.1", GoFloat(float64(float32(0.1)), 32))
+	assert.Equal(t, "math.NaN()", GoFloat(math.NaN(), 64))
+	assert.Equal(t, "math.Inf(1)", GoFloat(math.Inf(1), 64))
+	assert.Equal(t, "float32(math.Inf(-1))", GoFloat(math.Inf(-1), 32))
+}
+
+func TestWriteLogfmt(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "Plain",
+			in:   "abc",
+			want: "abc",
+		},
+		{
+			name: "Empty",
+			in:   "",
+			want: `""`,
+		},
+		{
+			name: "Space",
+			in:   "a b",
+			want: `"a b"`,
+		},
+		{
+			name: "Equal sign",
+			in:   "a=b",
+			want: `"a=b"`,
+		},
+		{
+			name: "Quote and newline",
+			in:   "a\"b\n",
+			want: `"a\"b\n"`,
+		},
+		{
+			name: "Unicode",
+			in:   "中文",
+			want: "中文",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &strings.Builder{}
+			WriteLogfmt(b, tt.in)
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
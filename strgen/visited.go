@@ -0,0 +1,26 @@
+package strgen
+
+// Visited holds the pointers being written by generated methods of -cycle flag,
+// a pointer met again while it is being written is a cycle. The zero value is ready to use.
+type Visited struct {
+	// ptrs is the path from the outermost pointer, it is as short as the nesting so a slice is enough
+	ptrs []interface{}
+}
+
+// Enter adds p to the pointers being written, it returns false without adding p if p is being written already.
+func (v *Visited) Enter(p interface{}) bool {
+	for _, q := range v.ptrs {
+		if q == p {
+			return false
+		}
+	}
+	v.ptrs = append(v.ptrs, p)
+	return true
+}
+
+// Leave removes p added by the last Enter.
+func (v *Visited) Leave(p interface{}) {
+	if n := len(v.ptrs); n > 0 && v.ptrs[n-1] == p {
+		v.ptrs = v.ptrs[:n-1]
+	}
+}
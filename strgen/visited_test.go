@@ -0,0 +1,25 @@
+package strgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisited(t *testing.T) {
+	type node struct{ next *node }
+	a, b := &node{}, &node{}
+	v := &Visited{}
+
+	assert.True(t, v.Enter(a))
+	assert.True(t, v.Enter(b))
+	assert.False(t, v.Enter(a))
+	v.Leave(b)
+	// b is not being written anymore, meeting it again is not a cycle
+	assert.True(t, v.Enter(b))
+	v.Leave(b)
+	v.Leave(a)
+	assert.True(t, v.Enter(a))
+	// pointers of different types are different even at the same address
+	assert.True(t, v.Enter(&a.next))
+}